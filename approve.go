@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// approvalStatuses are the run statuses that indicate a run is held until
+// someone approves it
+var approvalStatuses = []string{"waiting", "action_required"}
+
+// approveWorkflowRun approves a workflow run that is awaiting approval
+func approveWorkflowRun(ctx context.Context, fullName string, runID int) error {
+	url := fmt.Sprintf("%s/repos/%s/actions/runs/%d/approve", BaseURL, fullName, runID)
+	_, err := makeRequest(forOperation(ctx, opApprove), "POST", url, nil)
+	return err
+}
+
+// approveWaitingRuns approves every run in the repository that is waiting for
+// approval
+func approveWaitingRuns(ctx context.Context, repo Repository) Result {
+	progressf("Approving waiting runs in repository: %s\n", repo.Name)
+	result := newResult(repo, ActionApproved)
+
+	var waiting []WorkflowRun
+	for _, status := range approvalStatuses {
+		runs, err := listWorkflowRuns(ctx, repo.FullName, url.Values{"status": {status}})
+		if err != nil {
+			errorf("Error listing %s runs for %s: %v\n", status, repo.Name, err)
+			return result.fail(err)
+		}
+		waiting = append(waiting, runs...)
+	}
+
+	if len(waiting) == 0 {
+		return result.skip("no runs awaiting approval")
+	}
+
+	var firstErr error
+	for _, run := range waiting {
+		if err := approveWorkflowRun(ctx, repo.FullName, run.ID); err != nil {
+			errorf("Failed to approve workflow %s (Run ID: %d) in %s: %v\n", run.Name, run.ID, repo.Name, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		progressf("Approved workflow %s (Run ID: %d) in %s\n", run.Name, run.ID, repo.Name)
+		result.Workflow = run.Name
+		result.RunID = run.ID
+	}
+	if firstErr != nil {
+		return result.fail(firstErr)
+	}
+	return result
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Artifact represents a workflow artifact stored in a repository
+type Artifact struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	SizeBytes int64     `json:"size_in_bytes"`
+	Expired   bool      `json:"expired"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// listArtifacts fetches all workflow artifacts for a repository
+func listArtifacts(ctx context.Context, fullName string) ([]Artifact, error) {
+	url := fmt.Sprintf("%s/repos/%s/actions/artifacts?per_page=100", BaseURL, fullName)
+	return paginate(ctx, url, func(data []byte) ([]Artifact, error) {
+		var response struct {
+			Artifacts []Artifact `json:"artifacts"`
+		}
+		err := json.Unmarshal(data, &response)
+		return response.Artifacts, err
+	})
+}
+
+// deleteArtifact deletes a single workflow artifact
+func deleteArtifact(ctx context.Context, fullName string, artifactID int) error {
+	url := fmt.Sprintf("%s/repos/%s/actions/artifacts/%d", BaseURL, fullName, artifactID)
+	_, err := makeRequest(forOperation(ctx, opDeleteArtifact), "DELETE", url, nil)
+	return err
+}
+
+// cleanupArtifacts deletes the repository's artifacts created before the
+// -delete-artifacts-older-than cutoff. Already expired artifacts are skipped
+// because GitHub has removed their storage.
+func cleanupArtifacts(ctx context.Context, repo Repository) Result {
+	progressf("Cleaning up artifacts in repository: %s\n", repo.Name)
+	result := newResult(repo, ActionArtifactsDeleted)
+
+	artifacts, err := listArtifacts(ctx, repo.FullName)
+	if err != nil {
+		errorf("Error listing artifacts for %s: %v\n", repo.Name, err)
+		return result.fail(err)
+	}
+
+	cutoff := time.Now().Add(-config.DeleteArtifactsOlderThan)
+	var firstErr error
+	for _, artifact := range artifacts {
+		if artifact.Expired || !artifact.CreatedAt.Before(cutoff) {
+			continue
+		}
+
+		if err := deleteArtifact(ctx, repo.FullName, artifact.ID); err != nil {
+			errorf("Failed to delete artifact %s (ID: %d) in %s: %v\n", artifact.Name, artifact.ID, repo.Name, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		progressf("Deleted artifact %s (ID: %d) in %s\n", artifact.Name, artifact.ID, repo.Name)
+	}
+	if firstErr != nil {
+		return result.fail(firstErr)
+	}
+	return result
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// maxCachedResponses bounds the number of GET responses kept by responseCache
+const maxCachedResponses = 1000
+
+// cachedResponse is a successful GET response body and its headers
+type cachedResponse struct {
+	data   []byte
+	header http.Header
+}
+
+// getCache remembers successful GET responses by URL for the duration of a
+// sweep, so filters that need the same data do not fetch it twice. When full
+// the oldest entry is evicted.
+type getCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedResponse
+	order   []string
+}
+
+// responseCache is shared by every worker and cleared after each sweep
+var responseCache = &getCache{entries: make(map[string]cachedResponse)}
+
+// Get returns the cached response for url, if any
+func (c *getCache) Get(url string) (cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[url]
+	return entry, ok
+}
+
+// Put stores the response for url, evicting the oldest entry when full
+func (c *getCache) Put(url string, entry cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[url]; ok {
+		c.entries[url] = entry
+		return
+	}
+	if len(c.order) >= maxCachedResponses {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.entries[url] = entry
+	c.order = append(c.order, url)
+}
+
+// Clear drops every cached response
+func (c *getCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]cachedResponse)
+	c.order = nil
+}
+
+// clearMap removes every entry of m
+func clearMap(m *sync.Map) {
+	m.Range(func(key, _ any) bool {
+		m.Delete(key)
+		return true
+	})
+}
+
+// noCacheKey marks a context whose GETs must reach the API
+type noCacheKey struct{}
+
+// withoutCache returns a context whose GETs bypass responseCache, for callers
+// that poll the same URL for changes
+func withoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+// cacheable reports whether a request with ctx and method may use responseCache
+func cacheable(ctx context.Context, method string) bool {
+	return method == "GET" && ctx.Value(noCacheKey{}) == nil
+}
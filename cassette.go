@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// cassette is one recorded request/response pair stored on disk
+type cassette struct {
+	Method         string      `json:"method"`
+	URL            string      `json:"url"`
+	RequestHeader  http.Header `json:"request_header"`
+	StatusCode     int         `json:"status_code"`
+	ResponseHeader http.Header `json:"response_header"`
+	Body           string      `json:"body"`
+}
+
+// recordingTransport saves every exchange made through next under dir
+type recordingTransport struct {
+	dir  string
+	next http.RoundTripper
+}
+
+// replayingTransport answers requests from the cassettes under dir without
+// touching the network
+type replayingTransport struct {
+	dir string
+}
+
+// cassettePath returns the file holding the exchange for method and url
+func cassettePath(dir, method, url string) string {
+	sum := sha256.Sum256([]byte(method + " " + url))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	header := req.Header.Clone()
+	if header.Get("Authorization") != "" {
+		header.Set("Authorization", "REDACTED")
+	}
+
+	data, err := json.MarshalIndent(cassette{
+		Method:         req.Method,
+		URL:            req.URL.String(),
+		RequestHeader:  header,
+		StatusCode:     resp.StatusCode,
+		ResponseHeader: resp.Header,
+		Body:           string(body),
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(cassettePath(t.dir, req.Method, req.URL.String()), data); err != nil {
+		return nil, fmt.Errorf("failed to record cassette: %v", err)
+	}
+
+	return resp, nil
+}
+
+func (t *replayingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	data, err := os.ReadFile(cassettePath(t.dir, req.Method, req.URL.String()))
+	if err != nil {
+		return nil, fmt.Errorf("no recorded response for %s %s: %v", req.Method, req.URL, err)
+	}
+
+	var c cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid cassette for %s %s: %v", req.Method, req.URL, err)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", c.StatusCode, http.StatusText(c.StatusCode)),
+		StatusCode:    c.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        c.ResponseHeader,
+		Body:          io.NopCloser(bytes.NewReader([]byte(c.Body))),
+		ContentLength: int64(len(c.Body)),
+		Request:       req,
+	}, nil
+}
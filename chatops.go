@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// rerunCommand is the pull request comment that asks -serve to re-run the
+// pull request's latest failed workflow run
+const rerunCommand = "/rerun"
+
+// issueCommentEvent is the part of an issue_comment webhook payload -serve uses
+type issueCommentEvent struct {
+	Action string `json:"action"`
+	Issue  struct {
+		Number      int       `json:"number"`
+		PullRequest *struct{} `json:"pull_request"`
+	} `json:"issue"`
+	Comment struct {
+		Body string `json:"body"`
+		User struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	} `json:"comment"`
+	Repository Repository `json:"repository"`
+}
+
+// isRerunCommand reports whether event is a new pull request comment whose
+// first line is the rerun command
+func isRerunCommand(event issueCommentEvent) bool {
+	if event.Action != "created" || event.Issue.PullRequest == nil {
+		return false
+	}
+	first, _, _ := strings.Cut(strings.TrimSpace(event.Comment.Body), "\n")
+	return strings.TrimSpace(first) == rerunCommand
+}
+
+// collaboratorPermission fetches login's permission on the repository: admin,
+// write, read or none
+func collaboratorPermission(ctx context.Context, fullName, login string) (string, error) {
+	url := fmt.Sprintf("%s/repos/%s/collaborators/%s/permission", BaseURL, fullName, url.PathEscape(login))
+	data, err := makeRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	var response struct {
+		Permission string `json:"permission"`
+	}
+	err = json.Unmarshal(data, &response)
+	return response.Permission, err
+}
+
+// pullRequestHeadSHA fetches the commit a pull request's head points at
+func pullRequestHeadSHA(ctx context.Context, fullName string, number int) (string, error) {
+	url := fmt.Sprintf("%s/repos/%s/pulls/%d", BaseURL, fullName, number)
+	data, err := makeRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	var response struct {
+		Head struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	}
+	err = json.Unmarshal(data, &response)
+	return response.Head.SHA, err
+}
+
+// rerunPullRequest handles a rerun command: when the commenter can write to
+// the repository, it re-runs the latest failed run of the pull request's
+// head commit
+func rerunPullRequest(ctx context.Context, event issueCommentEvent) {
+	// -serve never ends a sweep, so responseCache would keep permissions,
+	// head commits and run statuses forever
+	lookupCtx := withoutCache(ctx)
+	repo, login, number := event.Repository, event.Comment.User.Login, event.Issue.Number
+	if repo.Owner.Login == "" {
+		repo.Owner.Login, _, _ = strings.Cut(repo.FullName, "/")
+	}
+
+	permission, err := collaboratorPermission(lookupCtx, repo.FullName, login)
+	if err != nil {
+		errorf("Error checking %s's permission on %s: %v\n", login, repo.FullName, err)
+		return
+	}
+	if permission != "admin" && permission != "write" {
+		logger.Warn("ignoring rerun command from user without write access", "repo", repo.FullName, "pull_request", number, "user", login, "permission", permission)
+		return
+	}
+
+	sha, err := pullRequestHeadSHA(lookupCtx, repo.FullName, number)
+	if err != nil {
+		errorf("Error fetching pull request %s#%d: %v\n", repo.FullName, number, err)
+		return
+	}
+	runs, err := listWorkflowRuns(lookupCtx, repo.FullName, url.Values{"head_sha": {sha}})
+	if err != nil {
+		errorf("Error listing runs of %s#%d: %v\n", repo.FullName, number, err)
+		return
+	}
+
+	for _, run := range runs {
+		if run.Status != "completed" || !isFailedConclusion(run.Conclusion) {
+			continue
+		}
+		progressf("Re-running %s#%d for %s\n", repo.FullName, number, login)
+		result := rerunRepoRun(ctx, repoRun{Repo: repo, Run: run})
+		if result.err == nil {
+			if err := checkpointState.Save(); err != nil {
+				errorf("Error writing checkpoint: %v\n", err)
+			}
+		}
+		return
+	}
+	progressf("No failed run to re-run for %s#%d\n", repo.FullName, number)
+}
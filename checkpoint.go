@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// checkpointEntry records a run as it was when this tool last re-triggered
+// it, and when each of the repository's workflows was last re-triggered for
+// -cooldown, keyed by workflow path
+type checkpointEntry struct {
+	Repo      string               `json:"repo"`
+	RunID     int                  `json:"run_id"`
+	UpdatedAt time.Time            `json:"updated_at"`
+	RerunAt   map[string]time.Time `json:"rerun_at,omitempty"`
+}
+
+// checkpoint is the -checkpoint file: the last run re-triggered in each
+// repository, so overlapping scheduled invocations do not re-run it twice
+type checkpoint struct {
+	mu   sync.Mutex
+	path string
+	runs map[string]checkpointEntry
+}
+
+// checkpointState is the loaded -checkpoint file, an in-memory checkpoint
+// when only -cooldown needs one, or nil when none is used
+var checkpointState *checkpoint
+
+// newMemoryCheckpoint returns a checkpoint that lives only as long as the
+// process, for -cooldown across -interval sweeps or -serve deliveries
+func newMemoryCheckpoint() *checkpoint {
+	return &checkpoint{runs: make(map[string]checkpointEntry)}
+}
+
+// loadCheckpoint reads the checkpoint at path; a missing file is an empty one
+func loadCheckpoint(path string) (*checkpoint, error) {
+	c := &checkpoint{path: path, runs: make(map[string]checkpointEntry)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []checkpointEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint file %s: %v", path, err)
+	}
+	for _, entry := range entries {
+		c.runs[entry.Repo] = entry
+	}
+	return c, nil
+}
+
+// Unchanged reports whether run is the run last re-triggered in the repository
+// and has not been updated since; a run that was re-run again elsewhere has a
+// newer updated_at and may be acted on again
+func (c *checkpoint) Unchanged(fullName string, run WorkflowRun) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.runs[fullName]
+	return ok && entry.RunID == run.ID && entry.UpdatedAt.Equal(run.UpdatedAt)
+}
+
+// InCooldown reports whether run's workflow was re-triggered in the
+// repository within -cooldown, and how long ago
+func (c *checkpoint) InCooldown(fullName string, run WorkflowRun) (ago time.Duration, cooling bool) {
+	if c == nil || config.Cooldown <= 0 {
+		return 0, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	last, ok := c.runs[fullName].RerunAt[run.Path]
+	if !ok {
+		return 0, false
+	}
+	ago = time.Since(last)
+	return ago, ago < config.Cooldown
+}
+
+// Record notes that run was re-triggered in the repository; a -dry-run
+// rerun is not recorded
+func (c *checkpoint) Record(fullName string, run WorkflowRun) {
+	if c == nil || config.DryRun {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rerunAt := c.runs[fullName].RerunAt
+	if rerunAt == nil {
+		rerunAt = make(map[string]time.Time)
+	}
+	rerunAt[run.Path] = time.Now()
+	c.runs[fullName] = checkpointEntry{Repo: fullName, RunID: run.ID, UpdatedAt: run.UpdatedAt, RerunAt: rerunAt}
+}
+
+// Save writes the checkpoint back to its file; an in-memory checkpoint has
+// none. The lock is held through the write so that concurrent saves from
+// -serve handlers land in order and an older snapshot never replaces a newer
+// one.
+func (c *checkpoint) Save() error {
+	if c == nil || c.path == "" {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries := make([]checkpointEntry, 0, len(c.runs))
+	for _, entry := range c.runs {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Repo < entries[j].Repo })
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(c.path, append(data, '\n'))
+}
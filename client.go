@@ -0,0 +1,237 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// httpClient is the shared client used for every GitHub API call
+var httpClient = &http.Client{}
+
+// requestCount is the number of API requests sent during this invocation
+var requestCount atomic.Int64
+
+// checkRequestBudget fails once -max-requests API calls have been made
+func checkRequestBudget() error {
+	if config.MaxRequests > 0 && requestCount.Load() >= int64(config.MaxRequests) {
+		return fmt.Errorf("request budget of %d API calls exhausted", config.MaxRequests)
+	}
+	return nil
+}
+
+// newHTTPClient builds the shared client from the proxy and TLS settings in cfg,
+// including an optional client certificate for servers that require mutual TLS.
+// With -record or -replay the transport is wrapped to save or serve cassettes.
+func newHTTPClient(cfg Config) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %v", cfg.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig := &tls.Config{}
+
+	if cfg.CACert != "" {
+		pem, err := os.ReadFile(cfg.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA cert: %v", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificates found in %s", cfg.CACert)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport.TLSClientConfig = tlsConfig
+
+	switch {
+	case cfg.Replay != "":
+		return &http.Client{Transport: &replayingTransport{dir: cfg.Replay}}, nil
+	case cfg.Record != "":
+		return &http.Client{Transport: &recordingTransport{dir: cfg.Record, next: transport}}, nil
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// doRequest applies the auth headers to req and sends it with the shared
+// client, pacing it through the -rps limiter, mutations through the -delay
+// limiter and searches through searchLimiter. Responses with a -retry-status
+// status are retried up to -retry-get or -retry-post times with the
+// -backoff-strategy delay. When the token is rate limited it retries with the
+// next token, or waits for the limit to reset when no other token has quota
+// left. Secondary rate limits back off separately (see
+// secondaryRateLimitDelay), and network failures are retried as described by
+// retryableNetworkError. Under -dry-run mutations (see isMutation) are not
+// sent at all (see dryRunResponse).
+func doRequest(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	attempt, secondary := 0, 0
+	if config.DryRun && isMutation(ctx) {
+		return dryRunResponse(req), nil
+	}
+
+	for {
+		if isMutation(ctx) {
+			if err := mutationLimiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+		if isSearchRequest(req) {
+			if err := searchLimiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		for key, value := range AuthHeader() {
+			req.Header.Set(key, value)
+		}
+		token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+
+		requestCount.Add(1)
+		stats.begin()
+		start := time.Now()
+		resp, err := httpClient.Do(req)
+		stats.end(time.Since(start))
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			if !retryableNetworkError(req.Method, err, attempt) {
+				if isUnreachable(err) {
+					return nil, fmt.Errorf("could not reach %s: check network/VPN: %w", req.URL.Host, err)
+				}
+				return nil, err
+			}
+			delay := backoffDelay(config.BackoffStrategy, attempt)
+			attempt++
+			logger.Info("retrying request after network error", "method", req.Method, "url", req.URL.String(), "error", err, "attempt", attempt, "delay", delay.Round(time.Millisecond))
+			emitEvent(ProgressEvent{Kind: EventRetrying, WaitMS: delay.Milliseconds(), Attempt: attempt, URL: req.URL.String()})
+			if err := sleepContext(ctx, delay); err != nil {
+				return nil, err
+			}
+			if req, err = rewindRequest(req); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		rotated := tokens.Observe(token, resp)
+		quota.Observe(resp)
+		var delay time.Duration
+		switch {
+		case isRateLimited(resp):
+			if !rotated {
+				waited, err := waitForRateLimitReset(ctx, resp)
+				if err != nil {
+					resp.Body.Close()
+					return nil, err
+				}
+				if !waited {
+					return resp, nil
+				}
+			}
+		case isSecondaryRateLimit(resp):
+			if secondary >= maxSecondaryRetries {
+				return resp, nil
+			}
+			delay, err = capServerWait(secondaryRateLimitDelay(resp, secondary))
+			if err != nil {
+				resp.Body.Close()
+				return nil, err
+			}
+			secondary++
+			logger.Warn("secondary rate limit exceeded, backing off", "method", req.Method, "url", req.URL.String(), "attempt", secondary, "delay", delay)
+			stats.rateLimitWait.Add(int64(delay))
+			stats.rateLimitWaits.Add(1)
+			emitEvent(ProgressEvent{Kind: EventRateLimited, WaitMS: delay.Milliseconds(), Attempt: secondary, URL: req.URL.String()})
+		default:
+			var retry bool
+			delay, retry = retryAfterStatus(resp, attempt)
+			if !retry {
+				return resp, nil
+			}
+			attempt++
+			logger.Info("retrying request", "method", req.Method, "url", req.URL.String(), "status", resp.StatusCode, "attempt", attempt, "delay", delay.Round(time.Millisecond))
+			emitEvent(ProgressEvent{Kind: EventRetrying, WaitMS: delay.Milliseconds(), Attempt: attempt, URL: req.URL.String()})
+		}
+
+		resp.Body.Close()
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+		if req, err = rewindRequest(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// isUnreachable reports whether err means the request never reached the
+// server: the host did not resolve or refused the connection
+func isUnreachable(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// retryableNetworkError reports whether a request that failed with err on
+// attempt may be tried again. An unreachable server never saw the request, so
+// any method is retried up to -retry-get times; a timeout may have been
+// delivered, so it follows the method's usual retry budget.
+func retryableNetworkError(method string, err error, attempt int) bool {
+	if isUnreachable(err) {
+		return attempt < config.RetryGet
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout() && attempt < maxRetries(method)
+}
+
+// isRateLimited reports whether resp was rejected by the primary rate limit
+func isRateLimited(resp *http.Response) bool {
+	return (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests) &&
+		resp.Header.Get("X-RateLimit-Remaining") == "0"
+}
+
+// rewindRequest returns a copy of req that can be sent again, with a fresh
+// body when it has one
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		clone.Body = body
+	}
+	return clone, nil
+}
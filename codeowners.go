@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+// codeownersPaths are the locations GitHub reads CODEOWNERS from, in order
+var codeownersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// codeownersRule is one line of a CODEOWNERS file
+type codeownersRule struct {
+	pattern string
+	owners  []string
+}
+
+// codeownersCache remembers each repository's CODEOWNERS rules, keyed by full
+// name, for the rest of the sweep; repositories without the file map to nil.
+// It is cleared with responseCache so edits show up in the next -interval
+// sweep.
+var codeownersCache sync.Map
+
+// fileOwners returns the owners CODEOWNERS assigns to file in the repository,
+// or nil when the repository has no CODEOWNERS file or no rule matches
+func fileOwners(ctx context.Context, repo Repository, file string) ([]string, error) {
+	rules, err := codeownersRules(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	// The last matching rule takes precedence
+	for i := len(rules) - 1; i >= 0; i-- {
+		if codeownersMatch(rules[i].pattern, file) {
+			return rules[i].owners, nil
+		}
+	}
+	return nil, nil
+}
+
+// codeownersRules fetches and parses the repository's CODEOWNERS file from the
+// first location that has one
+func codeownersRules(ctx context.Context, repo Repository) ([]codeownersRule, error) {
+	if cached, ok := codeownersCache.Load(repo.FullName); ok {
+		return cached.([]codeownersRule), nil
+	}
+
+	var rules []codeownersRule
+	for _, file := range codeownersPaths {
+		url := fmt.Sprintf("%s/repos/%s/contents/%s", BaseURL, repo.FullName, file)
+		data, err := makeRequest(ctx, "GET", url, nil)
+		if isStatus(err, http.StatusNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var response struct {
+			Content string `json:"content"`
+		}
+		if err := json.Unmarshal(data, &response); err != nil {
+			return nil, err
+		}
+		content, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(response.Content, "\n", ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s content: %v", file, err)
+		}
+		rules = parseCodeowners(string(content))
+		break
+	}
+	codeownersCache.Store(repo.FullName, rules)
+	return rules, nil
+}
+
+// parseCodeowners parses CODEOWNERS content, skipping comments, blank lines
+// and patterns without owners
+func parseCodeowners(content string) []codeownersRule {
+	var rules []codeownersRule
+	for _, line := range strings.Split(content, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		rules = append(rules, codeownersRule{pattern: fields[0], owners: fields[1:]})
+	}
+	return rules
+}
+
+// codeownersMatch reports whether pattern matches file the way CODEOWNERS
+// patterns do: a pattern containing a slash is anchored at the root, one
+// without matches a name at any depth, and a pattern naming a directory
+// (trailing "/" or "/**") matches everything below it. Within a segment "*"
+// and "?" are globs; "**" elsewhere is treated like "*".
+func codeownersMatch(pattern, file string) bool {
+	if pattern == "*" {
+		return true
+	}
+	pattern = strings.TrimSuffix(pattern, "**")
+	dirOnly := strings.HasSuffix(pattern, "/")
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.Trim(pattern, "/")
+	pattern = strings.ReplaceAll(pattern, "**", "*")
+
+	parts := strings.Split(strings.TrimPrefix(file, "/"), "/")
+	for i := 1; i <= len(parts); i++ {
+		if dirOnly && i == len(parts) {
+			break
+		}
+		candidate := parts[i-1]
+		if anchored {
+			candidate = strings.Join(parts[:i], "/")
+		}
+		if ok, _ := path.Match(pattern, candidate); ok {
+			return true
+		}
+	}
+	return false
+}
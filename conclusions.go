@@ -0,0 +1,40 @@
+package main
+
+// Conclusion buckets group GitHub's run conclusions for the summary
+const (
+	BucketPass  = "pass"
+	BucketFail  = "fail"
+	BucketOther = "other"
+)
+
+// passConclusions are the conclusions of runs that need no attention:
+// success, neutral and skipped. Runs concluding in -failed-conclusions
+// (failure, cancelled and timed_out by default) fail, as does
+// action_required with -action-required-failed. Everything else, such as
+// action_required, stale, startup_failure or a value GitHub adds later, is
+// other.
+var passConclusions = []string{"success", "neutral", "skipped"}
+
+// isFailedConclusion reports whether -only-failed treats conclusion as a
+// failure worth re-running
+func isFailedConclusion(conclusion string) bool {
+	if conclusion == "action_required" && config.ActionRequiredFailed {
+		return true
+	}
+	return containsString(config.FailedConclusions, conclusion)
+}
+
+// conclusionBucket classifies a run conclusion as pass, fail or other; a run
+// that has not completed has no conclusion and no bucket
+func conclusionBucket(conclusion string) string {
+	switch {
+	case conclusion == "":
+		return ""
+	case isFailedConclusion(conclusion):
+		return BucketFail
+	case containsString(passConclusions, conclusion):
+		return BucketPass
+	default:
+		return BucketOther
+	}
+}
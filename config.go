@@ -0,0 +1,541 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Config holds the settings resolved from command-line flags. Its JSON form is
+// also the schema of an organization profile in the -config file.
+type Config struct {
+	Orgs         stringList `json:"-"`
+	Enterprise   string     `json:"-"`
+	ParallelOrgs int        `json:"-"`
+	ConfigFile   string     `json:"-"`
+
+	Tokens          stringList `json:"-"`
+	TokenFile       string     `json:"token_file,omitempty"`
+	EnvFile         string     `json:"-"`
+	EnvFileOverride bool       `json:"-"`
+
+	Proxy      string `json:"proxy,omitempty"`
+	CACert     string `json:"ca_cert,omitempty"`
+	ClientCert string `json:"client_cert,omitempty"`
+	ClientKey  string `json:"client_key,omitempty"`
+	UserAgent  string `json:"user_agent,omitempty"`
+	Record     string `json:"record,omitempty"`
+	Replay     string `json:"replay,omitempty"`
+
+	MaxRequests     int           `json:"max_requests,omitempty"`
+	RetryGet        int           `json:"retry_get,omitempty"`
+	RetryPost       int           `json:"retry_post,omitempty"`
+	RetryStatus     commaList     `json:"retry_status,omitempty"`
+	RetryAfterCap   time.Duration `json:"retry_after_cap,omitempty"`
+	AbortOnLongWait bool          `json:"abort_on_long_wait,omitempty"`
+	SuccessStatuses stringList    `json:"success_statuses,omitempty"`
+	BackoffStrategy string        `json:"backoff_strategy,omitempty"`
+	Concurrency     int           `json:"concurrency,omitempty"`
+	RPS             float64       `json:"rps,omitempty"`
+	Delay           time.Duration `json:"delay,omitempty"`
+	MinRemaining    int           `json:"min_remaining,omitempty"`
+	Limit           int           `json:"limit,omitempty"`
+	MaxReposPerOrg  int           `json:"max_repos_per_org,omitempty"`
+	MaxRunPages     int           `json:"max_run_pages,omitempty"`
+	OldestFirst     bool          `json:"oldest_first,omitempty"`
+	FailFast        bool          `json:"fail_fast,omitempty"`
+
+	RepoTimeout time.Duration `json:"repo_timeout,omitempty"`
+
+	OnlyFailed           bool          `json:"only_failed,omitempty"`
+	FailedConclusions    commaList     `json:"failed_conclusions,omitempty"`
+	ActionRequiredFailed bool          `json:"action_required_failed,omitempty"`
+	RequireFailedJob     string        `json:"require_failed_job,omitempty"`
+	JobFallback          bool          `json:"job_fallback,omitempty"`
+	BestEffortListing    bool          `json:"best_effort_listing,omitempty"`
+	BufferListing        bool          `json:"buffer_listing,omitempty"`
+	AccessibleRepos      bool          `json:"accessible_repos,omitempty"`
+	SearchQuery          string        `json:"search_query,omitempty"`
+	ReposCursor          string        `json:"-"`
+	CursorMaxAge         time.Duration `json:"cursor_max_age,omitempty"`
+	Language             string        `json:"language,omitempty"`
+	MinSize              int           `json:"min_size,omitempty"`
+	MaxAttempts          int           `json:"max_attempts,omitempty"`
+	SkipIfNewerThan      time.Duration `json:"skip_if_newer_than,omitempty"`
+	SkipSelfTriggered    bool          `json:"skip_self_triggered,omitempty"`
+	OnlyDefaultBranch    bool          `json:"only_default_branch,omitempty"`
+	Created              string        `json:"created,omitempty"`
+	ExcludePRs           bool          `json:"exclude_prs,omitempty"`
+	CheckSuiteID         int64         `json:"check_suite_id,omitempty"`
+	WorkflowID           int64         `json:"workflow_id,omitempty"`
+	WorkflowFile         string        `json:"workflow_file,omitempty"`
+	SkipOfflineRunners   bool          `json:"skip_offline_runners,omitempty"`
+	HonorIgnoreFile      bool          `json:"honor_ignore_file,omitempty"`
+	CheckOrgPermissions  bool          `json:"check_org_permissions,omitempty"`
+
+	DeleteArtifactsOlderThan time.Duration `json:"delete_artifacts_older_than,omitempty"`
+	EnableWorkflow           string        `json:"enable_workflow,omitempty"`
+	DisableWorkflow          string        `json:"disable_workflow,omitempty"`
+	Approve                  bool          `json:"approve,omitempty"`
+	ApproveDeployments       bool          `json:"approve_deployments,omitempty"`
+	Comment                  string        `json:"comment,omitempty"`
+	CheckRunners             bool          `json:"check_runners,omitempty"`
+	Dispatch                 string        `json:"dispatch,omitempty"`
+	DispatchAllWorkflows     bool          `json:"dispatch_all_workflows,omitempty"`
+	ListWorkflows            bool          `json:"list_workflows,omitempty"`
+	SkipMissingWorkflow      bool          `json:"skip_missing_workflow,omitempty"`
+	OnlyReposWithWorkflow    string        `json:"only_repos_with_workflow,omitempty"`
+	RunsFile                 string        `json:"runs_file,omitempty"`
+	Plan                     string        `json:"-"`
+	Apply                    string        `json:"-"`
+	PlanMaxAge               time.Duration `json:"plan_max_age,omitempty"`
+	Repo                     string        `json:"-"`
+	RunID                    int           `json:"-"`
+	RerunSHA                 string        `json:"-"`
+	Follow                   bool          `json:"-"`
+	VerifyRuns               bool          `json:"verify_runs,omitempty"`
+	Count                    bool          `json:"-"`
+	Monitor                  bool          `json:"monitor,omitempty"`
+	Codeowners               bool          `json:"codeowners,omitempty"`
+	Checkpoint               string        `json:"-"`
+	ListOrgs                 bool          `json:"-"`
+	WithRepoCounts           bool          `json:"-"`
+
+	Output           string `json:"output,omitempty"`
+	OutputFile       string `json:"output_file,omitempty"`
+	OutputAppend     bool   `json:"output_append,omitempty"`
+	SummaryJSON      string `json:"summary_json,omitempty"`
+	PostHook         string `json:"post_hook,omitempty"`
+	PostHookRequired bool   `json:"post_hook_required,omitempty"`
+	FailuresFile     string `json:"failures_file,omitempty"`
+	GroupByOrg       bool   `json:"group_by_org,omitempty"`
+	TrackQuota       bool   `json:"track_quota,omitempty"`
+	Events           bool   `json:"events,omitempty"`
+	GroupBy          string `json:"group_by,omitempty"`
+	ReportSlowest    int    `json:"report_slowest,omitempty"`
+	Template         string `json:"template,omitempty"`
+	StrictJSON       bool   `json:"strict_json,omitempty"`
+	Bar              bool   `json:"bar,omitempty"`
+	Reason           string `json:"reason,omitempty"`
+	Quiet            bool   `json:"quiet,omitempty"`
+	QuietUnlessError bool   `json:"quiet_unless_error,omitempty"`
+	DryRun           bool   `json:"dry_run,omitempty"`
+	ShowRequests     bool   `json:"show_requests,omitempty"`
+
+	Interval      time.Duration `json:"interval,omitempty"`
+	Serve         string        `json:"serve,omitempty"`
+	WebhookSecret string        `json:"-"`
+	Cooldown      time.Duration `json:"cooldown,omitempty"`
+	Debug         bool          `json:"debug,omitempty"`
+	NoWarnings    bool          `json:"no_warnings,omitempty"`
+	PrintConfig   bool          `json:"-"`
+	Version       bool          `json:"-"`
+}
+
+// config is the effective configuration for this invocation
+var config Config
+
+// parseFlags populates config from the command-line arguments
+func parseFlags() {
+	flag.Var(&config.Orgs, "org", "organization to process; repeat for several")
+	flag.StringVar(&config.Enterprise, "enterprise", "", "process every organization in this enterprise")
+	flag.IntVar(&config.ParallelOrgs, "parallel-orgs", 1, "process this many organizations at once, each with its own -concurrency workers, sharing the -rps limit; organizations may not have -config profiles")
+	flag.StringVar(&config.ConfigFile, "config", "", "JSON file with per-organization profiles")
+	flag.Var(&config.Tokens, "token", "GitHub token to use; repeat to rotate between tokens on rate limit")
+	flag.StringVar(&config.EnvFile, "env-file", "", "load KEY=VALUE lines from this file into the environment first, e.g. "+tokenEnv+" and "+orgEnv+" for local development")
+	flag.BoolVar(&config.EnvFileOverride, "env-file-override", false, "let -env-file replace variables already set in the environment")
+	flag.StringVar(&config.TokenFile, "token-file", "", "file with one GitHub token per line")
+	flag.StringVar(&config.Proxy, "proxy", "", "HTTP(S) proxy URL (overrides HTTP_PROXY/HTTPS_PROXY)")
+	flag.StringVar(&config.CACert, "ca-cert", "", "path to a PEM CA bundle to trust in addition to the system roots")
+	flag.StringVar(&config.ClientCert, "client-cert", "", "path to a PEM client certificate for mutual TLS")
+	flag.StringVar(&config.ClientKey, "client-key", "", "path to the PEM private key for -client-cert")
+	flag.StringVar(&config.UserAgent, "user-agent", "", "User-Agent header sent with every request (default \"ReTriggerActions/<version>\")")
+	flag.StringVar(&config.Record, "record", "", "record every API exchange as a cassette file in this directory")
+	flag.StringVar(&config.Replay, "replay", "", "serve API responses from cassettes in this directory instead of the network")
+	flag.IntVar(&config.MaxRequests, "max-requests", 0, "stop paginating once this many API calls have been made (0 for no limit)")
+	flag.IntVar(&config.RetryGet, "retry-get", 3, "times to retry an idempotent (GET, HEAD, PUT, DELETE) request that failed with a -retry-status status")
+	flag.IntVar(&config.RetryPost, "retry-post", 0, "times to retry a POST that failed with a -retry-status status; POSTs such as reruns may already have taken effect")
+	config.RetryStatus = append(commaList(nil), defaultRetryStatuses...)
+	flag.DurationVar(&config.RetryAfterCap, "retry-after-cap", 5*time.Minute, "wait at most this long when GitHub asks for a longer wait through Retry-After or a rate limit reset (0 for no cap)")
+	flag.BoolVar(&config.AbortOnLongWait, "abort-on-long-wait", false, "fail the request instead of capping a wait longer than -retry-after-cap")
+	flag.Var(&config.SuccessStatuses, "success-status", "also accept these statuses as success for an operation, as operation=status[,status] (operations: rerun, dispatch, approve, enable-workflow, disable-workflow, review-deployments, delete-artifact); repeatable")
+	flag.Var(&config.RetryStatus, "retry-status", "comma-separated HTTP statuses retried with backoff (e.g. 408,429,500,502,503,504)")
+	flag.StringVar(&config.BackoffStrategy, "backoff-strategy", BackoffFullJitter, "retry backoff: exponential, exponential+jitter or full-jitter")
+	flag.IntVar(&config.Concurrency, "concurrency", 1, "number of repositories to process in parallel")
+	flag.Float64Var(&config.RPS, "rps", 0, "maximum API requests per second across all workers (0 for no limit)")
+	flag.DurationVar(&config.Delay, "delay", 0, "wait this long between repositories; with -concurrency above 1, the minimum interval between mutating requests such as reruns")
+	flag.IntVar(&config.MinRemaining, "min-remaining", 0, "stop starting new work once the token's remaining rate limit drops to this value (0 to use the whole quota)")
+	flag.IntVar(&config.MaxReposPerOrg, "max-repos-per-org", 0, "process at most this many repositories per organization, after the repository filters and in listing order, to try a change on a reproducible sample (0 for no cap)")
+	flag.IntVar(&config.Limit, "limit", 0, "re-run at most this many workflow runs (0 for no limit); applied after -oldest-first sorting")
+	flag.BoolVar(&config.OldestFirst, "oldest-first", false, "re-run the repositories whose latest run is oldest first")
+	flag.BoolVar(&config.OnlyFailed, "only-failed", false, "only re-run workflows whose latest run concluded in one of -failed-conclusions")
+	config.FailedConclusions = append(commaList(nil), defaultFailedConclusions...)
+	flag.Var(&config.FailedConclusions, "failed-conclusions", "comma-separated run conclusions -only-failed counts as failed")
+	flag.StringVar(&config.RequireFailedJob, "require-failed-job", "", "only re-run a run when its job with this name failed in the latest attempt, such as \"deploy\"; matrix jobs match by base name")
+	flag.BoolVar(&config.JobFallback, "job-fallback", false, "when a run's jobs cannot be read (404 or 403), skip job-level checks such as -require-failed-job and re-run the whole run instead of failing")
+	flag.BoolVar(&config.ActionRequiredFailed, "action-required-failed", false, "treat the action_required conclusion, usually a pending approval, as a failure for -only-failed and the summary instead of as other")
+	flag.BoolVar(&config.BufferListing, "buffer-listing", false, "list every repository before processing any, in listing order, instead of processing repositories as their pages arrive")
+	flag.IntVar(&config.MaxRunPages, "max-run-pages", 5, "scan at most this many pages of 100 runs per repository when listing runs, so huge run histories cannot exhaust the rate limit (0 for no cap)")
+	flag.StringVar(&config.SearchQuery, "search-query", "", "select repositories with GitHub search syntax (e.g. \"topic:deployable language:go\"), scoped to each organization; the search API returns at most 1000 repositories")
+	flag.StringVar(&config.ReposCursor, "repos-cursor", "", "JSON file remembering each organization's repositories so later sweeps list only new ones")
+	flag.DurationVar(&config.CursorMaxAge, "cursor-max-age", 24*time.Hour, "list every repository again once the -repos-cursor entry is older than this, to notice deleted, renamed or changed repositories")
+	flag.BoolVar(&config.BestEffortListing, "best-effort-listing", false, "if a repository listing page fails after retries, accept the repositories already listed instead of recording an error (and, with -buffer-listing, skipping the organization)")
+	flag.StringVar(&config.Created, "created", "", "only consider runs created in this date range, in GitHub search syntax (e.g. \">=2024-01-01\" or \"2024-01-01..2024-01-31\")")
+	flag.Int64Var(&config.WorkflowID, "workflow-id", 0, "only consider runs of the workflow with this ID")
+	flag.StringVar(&config.WorkflowFile, "workflow-file", "", "only consider runs of the workflow with this file name (e.g. ci.yml)")
+	flag.BoolVar(&config.ExcludePRs, "exclude-prs", false, "ignore runs triggered by pull requests")
+	flag.Int64Var(&config.CheckSuiteID, "check-suite-id", 0, "only consider runs belonging to this check suite")
+	flag.BoolVar(&config.AccessibleRepos, "accessible-repos", false, "list the organization's repositories the token's user can access as a member or collaborator, for tokens without organization-wide read access")
+	flag.StringVar(&config.Language, "language", "", "only process repositories whose primary language matches (case-insensitive)")
+	flag.IntVar(&config.MinSize, "min-size", 0, "only process repositories of at least this size in KB, skipping empty placeholders without looking up their runs (0 for no filter)")
+	flag.IntVar(&config.MaxAttempts, "max-attempts", 0, "skip runs that have already been attempted this many times (0 for no limit)")
+	flag.DurationVar(&config.SkipIfNewerThan, "skip-if-newer-than", 0, "skip repositories whose latest run was updated within this long ago (e.g. 1h)")
+	flag.BoolVar(&config.SkipSelfTriggered, "skip-self-triggered", false, "skip runs whose triggering actor is the account behind the (first) token, so scheduled sweeps do not re-run their own reruns")
+	flag.BoolVar(&config.OnlyDefaultBranch, "only-default-branch", false, "only consider runs on each repository's own default branch")
+	flag.DurationVar(&config.RepoTimeout, "repo-timeout", 0, "abandon a repository that takes longer than this, including waits and retries (0 for no limit)")
+	flag.BoolVar(&config.FailFast, "fail-fast", false, "stop at the first repository error and exit non-zero")
+	flag.DurationVar(&config.DeleteArtifactsOlderThan, "delete-artifacts-older-than", 0, "delete artifacts older than this instead of re-running workflows (e.g. 720h)")
+	flag.StringVar(&config.EnableWorkflow, "enable-workflow", "", "enable the workflow with this file name in every repository")
+	flag.StringVar(&config.DisableWorkflow, "disable-workflow", "", "disable the workflow with this file name in every repository")
+	flag.StringVar(&config.Output, "output", "text", "output format: text, json, jsonl, csv or template")
+	flag.StringVar(&config.OutputFile, "output-file", "", "write structured output to this file instead of stdout")
+	flag.BoolVar(&config.OutputAppend, "output-append", false, "with -output jsonl, add each sweep's results to -output-file after an {\"iteration\", \"timestamp\"} marker line instead of replacing the file")
+	flag.StringVar(&config.FailuresFile, "failures-file", "", "write the failed repositories as \"repo,run_id\" lines to this file, ready to pass back as -runs-file for a retry pass")
+	flag.StringVar(&config.SummaryJSON, "summary-json", "", "write aggregate stats for each sweep to this JSON file")
+	flag.StringVar(&config.PostHook, "post-hook", "", "after each sweep, run this shell command with the -summary-json document on stdin and its counts in RETRIGGER_TOTAL, RETRIGGER_RERUN, RETRIGGER_SKIPPED and RETRIGGER_ERRORS")
+	flag.BoolVar(&config.PostHookRequired, "post-hook-required", false, "abort when the -post-hook command fails instead of only logging it")
+	flag.BoolVar(&config.StrictJSON, "strict-json", false, "validate every result against the documented result schema and fail the run on a mismatch")
+	flag.StringVar(&config.GroupBy, "group-by", "", "with text output, list the results after each sweep under headings; \"conclusion\" groups them into failing, passing, other and no runs")
+	flag.BoolVar(&config.Events, "events", false, "write a JSON line to stderr as each repository starts and finishes and as requests are rate limited or retried")
+	flag.BoolVar(&config.TrackQuota, "track-quota", false, "log the remaining rate-limit quota every 10s of requests and add the samples to -summary-json, to see how fast a sweep drains it")
+	flag.BoolVar(&config.GroupByOrg, "group-by-org", false, "print per-organization counts after each sweep and break down -summary-json by organization")
+	flag.IntVar(&config.ReportSlowest, "report-slowest", 0, "after each sweep, list this many repositories that took longest to process")
+	flag.StringVar(&config.Template, "template", "{{.Repo}} {{.Action}}\n", "Go template applied to each result with -output template")
+	flag.BoolVar(&config.Approve, "approve", false, "approve runs awaiting approval instead of re-running workflows")
+	flag.BoolVar(&config.ApproveDeployments, "approve-deployments", false, "approve the pending environment deployments of waiting runs instead of re-running workflows")
+	flag.StringVar(&config.Comment, "comment", "Approved by retrigger-actions", "review comment recorded with -approve-deployments")
+	flag.BoolVar(&config.CheckRunners, "check-runners", false, "report repositories whose self-hosted runners are all offline instead of re-running workflows")
+	flag.BoolVar(&config.CheckOrgPermissions, "check-org-permissions", false, "warn before processing an organization whose Actions policy will make reruns fail")
+	flag.BoolVar(&config.HonorIgnoreFile, "honor-ignore-file", true, "skip repositories that contain a "+ignoreFileName+" file at their root")
+	flag.BoolVar(&config.SkipOfflineRunners, "skip-offline-runners", false, "skip re-running repositories whose self-hosted runners are all offline")
+	flag.StringVar(&config.Dispatch, "dispatch", "", "dispatch the workflow with this file name on each repository's default branch instead of re-running")
+	flag.BoolVar(&config.DispatchAllWorkflows, "dispatch-all-workflows", false, "dispatch every active workflow of each repository on its default branch, skipping workflows without a workflow_dispatch trigger")
+	flag.BoolVar(&config.ListWorkflows, "list-workflows", false, "list each repository's workflows (name, file path and state) instead of re-running, to find the values for -workflow-file and -dispatch")
+	flag.StringVar(&config.OnlyReposWithWorkflow, "only-repos-with-workflow", "", "skip repositories without a workflow with this file name (e.g. ci.yml) before looking up their runs")
+	flag.BoolVar(&config.SkipMissingWorkflow, "skip-missing-workflow", false, "with -dispatch, quietly skip repositories that do not have the workflow")
+	flag.StringVar(&config.Plan, "plan", "", "find the runs to re-run without re-running them and write them to this plan file for review")
+	flag.StringVar(&config.Apply, "apply", "", "re-run exactly the runs in this -plan file, skipping any that changed since it was written")
+	flag.DurationVar(&config.PlanMaxAge, "plan-max-age", time.Hour, "warn when the -apply plan is older than this")
+	flag.StringVar(&config.RunsFile, "runs-file", "", "re-run exactly the runs listed in this file, one \"repo,run_id\" per line")
+	flag.StringVar(&config.Checkpoint, "checkpoint", "", "JSON file remembering the runs re-triggered so far; a run is not re-triggered again until its updated_at changes")
+	flag.BoolVar(&config.Monitor, "monitor", false, "only report each repository's latest run, re-running nothing, and exit with status 1 if any of them failed")
+	flag.BoolVar(&config.Codeowners, "codeowners", false, "with -monitor, name the CODEOWNERS owners of each failing workflow file")
+	flag.BoolVar(&config.Count, "count", false, "only print how many repositories match the filters, making no changes, and exit")
+	flag.StringVar(&config.Repo, "repo", "", "repository (\"name\" or \"owner/name\") of the -run-id or -rerun-sha run")
+	flag.IntVar(&config.RunID, "run-id", 0, "re-run only this run of -repo")
+	flag.StringVar(&config.RerunSHA, "rerun-sha", "", "re-run only the newest run of -repo for this commit SHA")
+	flag.BoolVar(&config.Follow, "follow", false, "with -run-id, wait for the rerun and print each job's log as it completes")
+	flag.BoolVar(&config.VerifyRuns, "verify-runs", false, "with -runs-file or -run-id, check each run exists in its repository before re-running it")
+	flag.BoolVar(&config.ListOrgs, "list-orgs", false, "list the organizations the token can access and exit")
+	flag.BoolVar(&config.WithRepoCounts, "with-repo-counts", false, "include repository counts with -list-orgs")
+	flag.DurationVar(&config.Interval, "interval", 0, "re-process the organization every interval until interrupted (e.g. 1h); 0 runs once")
+	flag.StringVar(&config.Serve, "serve", "", "listen on this address (e.g. \":8080\") for workflow_run webhooks and re-run failed runs as they complete, instead of sweeping")
+	flag.StringVar(&config.WebhookSecret, "webhook-secret", "", "secret the -serve webhooks are signed with (default $"+webhookSecretEnv+")")
+	flag.DurationVar(&config.Cooldown, "cooldown", 0, "skip re-running a repository's workflow within this period of this tool last re-running it (e.g. 30m), tracked in -checkpoint or else in memory; -serve defaults to 30m")
+	flag.BoolVar(&config.Bar, "bar", false, "show a progress bar with an ETA on stderr (terminal and text output only)")
+	flag.StringVar(&config.Reason, "reason", "", "why this sweep runs (e.g. \"incident-4821\"); recorded with every result and logged with each rerun")
+	flag.BoolVar(&config.Quiet, "quiet", false, "print only errors and summaries")
+	flag.BoolVar(&config.QuietUnlessError, "quiet-unless-error", false, "print nothing unless the sweep had an error, then print its full output")
+	flag.BoolVar(&config.DryRun, "dry-run", false, "make no changes: read as usual but do not send reruns or other mutating requests")
+	flag.BoolVar(&config.ShowRequests, "show-requests", false, "with -dry-run, print the method and URL of every mutating request that would be sent")
+	flag.BoolVar(&config.NoWarnings, "no-warnings", false, "suppress advisory warnings about the configuration")
+	flag.BoolVar(&config.PrintConfig, "print-config", false, "print the resolved configuration as JSON with secrets redacted and exit")
+	flag.BoolVar(&config.Version, "version", false, "print version and build information and exit")
+	flag.BoolVar(&config.Debug, "debug", false, "enable debug logging")
+	flag.Parse()
+
+	if config.EnvFile != "" {
+		if err := loadEnvFile(config.EnvFile, config.EnvFileOverride); err != nil {
+			fmt.Printf("Error loading env file: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if config.WebhookSecret == "" {
+		config.WebhookSecret = os.Getenv(webhookSecretEnv)
+	}
+}
+
+// recommendedConcurrency is the -concurrency above which GitHub's secondary
+// rate limits usually make a sweep slower rather than faster
+const recommendedConcurrency = 10
+
+// configWarnings returns advisory notes about settings that are valid but
+// likely to backfire
+func configWarnings(cfg Config) []string {
+	var warnings []string
+	if cfg.Concurrency > recommendedConcurrency {
+		warnings = append(warnings, fmt.Sprintf(
+			"-concurrency %d exceeds the recommended %d; GitHub will likely rate-limit the burst and the sweep will slow down. Consider a lower -concurrency with -rps to pace requests.",
+			cfg.Concurrency, recommendedConcurrency))
+	}
+	return warnings
+}
+
+// validateConfig rejects flag combinations that cannot be honored
+func validateConfig(cfg Config) error {
+	modes := 0
+	if cfg.DeleteArtifactsOlderThan > 0 {
+		modes++
+	}
+	if cfg.EnableWorkflow != "" {
+		modes++
+	}
+	if cfg.DisableWorkflow != "" {
+		modes++
+	}
+	if cfg.Approve {
+		modes++
+	}
+	if cfg.CheckRunners {
+		modes++
+	}
+	if cfg.RunsFile != "" {
+		modes++
+	}
+	if cfg.Dispatch != "" {
+		modes++
+	}
+	if cfg.ApproveDeployments {
+		modes++
+	}
+	if cfg.DispatchAllWorkflows {
+		modes++
+	}
+	if cfg.Count {
+		modes++
+	}
+	if cfg.ListWorkflows {
+		modes++
+	}
+	if cfg.Monitor {
+		modes++
+	}
+	if cfg.RunID > 0 {
+		modes++
+	}
+	if cfg.RerunSHA != "" {
+		modes++
+	}
+	if cfg.Apply != "" {
+		modes++
+	}
+	if modes > 1 {
+		return fmt.Errorf("-delete-artifacts-older-than, -enable-workflow, -disable-workflow, -approve, -approve-deployments, -check-runners, -runs-file, -run-id, -rerun-sha, -apply, -dispatch, -dispatch-all-workflows, -list-workflows, -monitor and -count are mutually exclusive")
+	}
+	if (cfg.RunID > 0 || cfg.RerunSHA != "") != (cfg.Repo != "") {
+		return fmt.Errorf("-run-id or -rerun-sha and -repo must be provided together")
+	}
+	if cfg.SearchQuery != "" && (cfg.AccessibleRepos || cfg.ReposCursor != "") {
+		return fmt.Errorf("-search-query cannot be combined with -accessible-repos or -repos-cursor")
+	}
+	if cfg.Serve != "" && cfg.WebhookSecret == "" {
+		return fmt.Errorf("-serve requires -webhook-secret or %s", webhookSecretEnv)
+	}
+	if cfg.Plan != "" && (modes > 0 || cfg.Interval > 0 || cfg.Serve != "") {
+		return fmt.Errorf("-plan records the reruns of a single sweep and cannot be combined with another mode, -interval or -serve")
+	}
+	if cfg.Codeowners && !cfg.Monitor {
+		return fmt.Errorf("-codeowners requires -monitor")
+	}
+	if cfg.PostHookRequired && cfg.PostHook == "" {
+		return fmt.Errorf("-post-hook-required requires -post-hook")
+	}
+	if cfg.Follow && cfg.RunID <= 0 {
+		return fmt.Errorf("-follow requires -run-id")
+	}
+
+	if cfg.Created != "" && !createdPattern.MatchString(cfg.Created) {
+		return fmt.Errorf("invalid -created %q: expected a date such as 2024-01-01, optionally prefixed by >, >=, < or <=, or a range such as 2024-01-01..2024-01-31", cfg.Created)
+	}
+
+	if cfg.ShowRequests && !cfg.DryRun {
+		return fmt.Errorf("-show-requests requires -dry-run")
+	}
+
+	if cfg.Record != "" && cfg.Replay != "" {
+		return fmt.Errorf("-record and -replay are mutually exclusive")
+	}
+	if (cfg.ClientCert == "") != (cfg.ClientKey == "") {
+		return fmt.Errorf("-client-cert and -client-key must be provided together")
+	}
+
+	for _, status := range cfg.RetryStatus {
+		if code, err := strconv.Atoi(status); err != nil || code < 100 || code > 599 {
+			return fmt.Errorf("invalid -retry-status %q: expected HTTP status codes", status)
+		}
+	}
+
+	if _, err := parseSuccessStatuses(cfg.SuccessStatuses); err != nil {
+		return err
+	}
+
+	switch cfg.BackoffStrategy {
+	case BackoffExponential, BackoffExponentialJitter, BackoffFullJitter:
+	default:
+		return fmt.Errorf("unknown -backoff-strategy %q", cfg.BackoffStrategy)
+	}
+
+	switch cfg.Output {
+	case "text", "json", "jsonl", "csv", "template":
+	default:
+		return fmt.Errorf("unknown -output format %q", cfg.Output)
+	}
+	if cfg.WorkflowID > 0 && cfg.WorkflowFile != "" {
+		return fmt.Errorf("-workflow-id and -workflow-file are mutually exclusive")
+	}
+
+	if cfg.StrictJSON && cfg.Output != "json" && cfg.Output != "jsonl" {
+		return fmt.Errorf("-strict-json requires -output json or jsonl")
+	}
+	if cfg.ParallelOrgs < 1 {
+		return fmt.Errorf("-parallel-orgs must be at least 1")
+	}
+	if cfg.ParallelOrgs > 1 && cfg.Bar {
+		return fmt.Errorf("-bar cannot track several organizations at once; drop it or -parallel-orgs")
+	}
+	if cfg.GroupBy != "" && cfg.GroupBy != "conclusion" {
+		return fmt.Errorf("invalid -group-by %q: must be conclusion", cfg.GroupBy)
+	}
+	if cfg.GroupBy != "" && cfg.Output != "text" {
+		return fmt.Errorf("-group-by requires -output text")
+	}
+	if cfg.OutputAppend && (cfg.Output != "jsonl" || cfg.OutputFile == "") {
+		return fmt.Errorf("-output-append requires -output jsonl and -output-file")
+	}
+	if cfg.OutputFile != "" && cfg.Output == "text" {
+		return fmt.Errorf("-output-file requires a structured -output format")
+	}
+	return nil
+}
+
+// FileConfig is the schema of the -config file. Each organization maps to a
+// profile object using Config's JSON field names; fields it sets override the
+// command-line values for that organization only.
+//
+//	{"orgs": {"acme": {"only_failed": true}, "widgets": {"concurrency": 4}}}
+type FileConfig struct {
+	Orgs map[string]json.RawMessage `json:"orgs"`
+}
+
+// orgProfile is an organization together with the config to process it
+// with; Overridden is set when the -config file has a profile for it
+type orgProfile struct {
+	Org        string
+	Config     Config
+	Overridden bool
+}
+
+// loadProfiles returns the organizations to process in order, each with its
+// effective config. Organizations come from the -config file and -org flags,
+// falling back to the Organization constant, then $GITHUB_ORG and then the
+// owner of the origin remote when run inside a git working tree.
+func loadProfiles(base Config) ([]orgProfile, error) {
+	overrides := map[string]json.RawMessage{}
+	if base.ConfigFile != "" {
+		data, err := os.ReadFile(base.ConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		var file FileConfig
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %v", base.ConfigFile, err)
+		}
+		overrides = file.Orgs
+	}
+
+	orgs := append([]string(nil), base.Orgs...)
+	var fileOrgs []string
+	for org := range overrides {
+		fileOrgs = append(fileOrgs, org)
+	}
+	sort.Strings(fileOrgs)
+	for _, org := range fileOrgs {
+		if !containsString(orgs, org) {
+			orgs = append(orgs, org)
+		}
+	}
+	if len(orgs) == 0 {
+		org := Organization
+		if org == "" {
+			org = os.Getenv(orgEnv)
+		}
+		if org == "" {
+			org = originOrg()
+		}
+		orgs = []string{org}
+	}
+
+	var profiles []orgProfile
+	for _, org := range orgs {
+		cfg, err := mergeProfile(base, overrides[org])
+		if err != nil {
+			return nil, fmt.Errorf("invalid profile for %s: %v", org, err)
+		}
+		profiles = append(profiles, orgProfile{Org: org, Config: cfg, Overridden: len(overrides[org]) > 0})
+	}
+	return profiles, nil
+}
+
+// processWideKeys are the profile keys of settings read once for the whole
+// invocation: the tokens and HTTP client, the -rps and -delay limiters every
+// organization shares, the output and reports written after each sweep, the
+// schedule and logging. A profile may not set them, since the value would be
+// silently ignored.
+var processWideKeys = []string{
+	"rps", "delay", "token_file", "proxy", "ca_cert", "client_cert", "client_key", "record", "replay", "success_statuses",
+	"output", "output_file", "output_append", "summary_json", "post_hook", "post_hook_required", "failures_file",
+	"group_by_org", "group_by", "report_slowest", "template", "strict_json", "track_quota", "events", "bar",
+	"quiet_unless_error", "runs_file", "verify_runs", "plan_max_age", "monitor",
+	"interval", "serve", "debug", "no_warnings",
+}
+
+// mergeProfile applies the fields set in a profile on top of base. Settings
+// that apply to the whole process (see processWideKeys) are rejected.
+func mergeProfile(base Config, profile json.RawMessage) (Config, error) {
+	cfg := base
+	if len(profile) == 0 {
+		return cfg, nil
+	}
+
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(profile, &keys); err != nil {
+		return Config{}, err
+	}
+	for _, key := range processWideKeys {
+		if _, ok := keys[key]; ok {
+			return Config{}, fmt.Errorf("%s cannot be set per organization: it applies to the whole process, so set it on the command line", key)
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(profile))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return Config{}, err
+	}
+	return cfg, validateConfig(cfg)
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMergeProfileRejectsProcessWideKeys(t *testing.T) {
+	for _, key := range processWideKeys {
+		profile := json.RawMessage(`{"` + key + `": null}`)
+		_, err := mergeProfile(Config{}, profile)
+		if err == nil || !strings.HasPrefix(err.Error(), key+" cannot be set per organization") {
+			t.Errorf("profile setting %s: error %v, want it rejected", key, err)
+		}
+	}
+}
+
+func TestMergeProfileKeepsBase(t *testing.T) {
+	base := Config{Concurrency: 4, RPS: 2, Reason: "nightly", BackoffStrategy: BackoffExponential, Output: "text", ParallelOrgs: 1}
+	cfg, err := mergeProfile(base, json.RawMessage(`{"reason": "acme only"}`))
+	if err != nil {
+		t.Fatalf("mergeProfile: %v", err)
+	}
+	if cfg.Reason != "acme only" || cfg.Concurrency != 4 || cfg.RPS != 2 {
+		t.Errorf("merged config has reason %q, concurrency %d, rps %v; want the profile's reason over the base", cfg.Reason, cfg.Concurrency, cfg.RPS)
+	}
+}
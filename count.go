@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// countMatches prints how many repositories pass the repository and run
+// filters, out of how many were listed, without re-running anything. Like a
+// sweep, it looks at no more than -max-repos-per-org of each organization. Listing
+// and lookup errors are logged and do not change the exit status.
+func countMatches(ctx context.Context, profiles []orgProfile) {
+	base, out := config, progress
+	progress = io.Discard
+	defer func() { config, progress = base, out }()
+
+	total, matched := 0, 0
+	for _, profile := range profiles {
+		config = profile.Config
+		repos, err := getRepositories(ctx, profile.Org)
+		if err != nil {
+			logger.Warn("listing repositories failed", "org", profile.Org, "error", err)
+			continue
+		}
+		total += len(repos)
+
+		ctx, cancel := context.WithCancel(ctx)
+		results, _ := runPool(ctx, cancel, capRepositories(filterRepositories(repos)), func(ctx context.Context, repo Repository) Result {
+			if _, result, ok := selectRun(ctx, repo); !ok {
+				return result
+			}
+			return newResult(repo, "")
+		})
+		cancel()
+
+		for _, result := range results {
+			if result.Action == "" {
+				matched++
+			} else if result.err != nil {
+				logger.Warn("run lookup failed", "repo", result.Repo, "error", result.err)
+			}
+		}
+	}
+
+	fmt.Fprintf(out, "%d repos match, %d total\n", matched, total)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// The -repos-cursor file makes frequent sweeps of large organizations cheaper
+// by remembering each organization's repository list. GitHub's organization
+// listing has no "since" parameter, but it can be sorted newest first, so a
+// sweep with a fresh cursor pages only until it reaches a repository it
+// already knows and takes the rest from the cursor.
+//
+// The trade-off is staleness: deleted, renamed, transferred or archived
+// repositories and changed metadata (default branch, language) go unnoticed
+// until the next full listing, which happens once the cursor is older than
+// -cursor-max-age. Keep the age short when such changes matter.
+
+// errStopPaging ends a listing early once the rest is known
+var errStopPaging = errors.New("stop paging")
+
+// repoCursor is an organization's repository list as of ListedAt, the time of
+// its last full listing
+type repoCursor struct {
+	ListedAt time.Time    `json:"listed_at"`
+	Repos    []Repository `json:"repos"`
+}
+
+// cursorFile is the -repos-cursor file, keyed by organization
+type cursorFile struct {
+	mu      sync.Mutex
+	path    string
+	cursors map[string]repoCursor
+}
+
+// repoCursors is the loaded -repos-cursor file, or nil when none is used
+var repoCursors *cursorFile
+
+// loadCursorFile reads the cursor file at path; a missing file is an empty one
+func loadCursorFile(path string) (*cursorFile, error) {
+	f := &cursorFile{path: path, cursors: make(map[string]repoCursor)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &f.cursors); err != nil {
+		return nil, fmt.Errorf("invalid cursor file %s: %v", path, err)
+	}
+	return f, nil
+}
+
+// Save writes the cursors back to their file
+func (f *cursorFile) Save() error {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	data, err := json.MarshalIndent(f.cursors, "", "  ")
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(f.path, append(data, '\n'))
+}
+
+// eachRepositoryPageFromCursor lists the organization's repositories like
+// eachRepositoryPage, using and updating its cursor: with a fresh cursor only
+// the repositories created since are fetched, otherwise everything is listed
+// and the cursor is rebuilt
+func eachRepositoryPageFromCursor(ctx context.Context, org string, each func([]Repository) error) error {
+	repoCursors.mu.Lock()
+	cursor, ok := repoCursors.cursors[org]
+	repoCursors.mu.Unlock()
+
+	url := fmt.Sprintf("%s/orgs/%s/repos?sort=created&direction=desc&per_page=100", BaseURL, org)
+
+	if !ok || time.Since(cursor.ListedAt) > config.CursorMaxAge {
+		listedAt := time.Now()
+		var all []Repository
+		err := paginateEach(ctx, url, parseRepositories, func(batch []Repository) error {
+			all = append(all, batch...)
+			return each(batch)
+		})
+		if err == nil {
+			repoCursors.mu.Lock()
+			repoCursors.cursors[org] = repoCursor{ListedAt: listedAt, Repos: all}
+			repoCursors.mu.Unlock()
+		}
+		return err
+	}
+
+	known := make(map[int64]bool, len(cursor.Repos))
+	for _, repo := range cursor.Repos {
+		known[repo.ID] = true
+	}
+
+	var added []Repository
+	err := paginateEach(ctx, url, parseRepositories, func(batch []Repository) error {
+		var fresh []Repository
+		reachedKnown := false
+		for _, repo := range batch {
+			if known[repo.ID] {
+				reachedKnown = true
+				break
+			}
+			fresh = append(fresh, repo)
+		}
+		if len(fresh) > 0 {
+			added = append(added, fresh...)
+			if err := each(fresh); err != nil {
+				return err
+			}
+		}
+		if reachedKnown {
+			return errStopPaging
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopPaging) {
+		return err
+	}
+	logger.Debug("listed repositories from cursor", "org", org, "new", len(added), "cached", len(cursor.Repos))
+
+	if err := each(cursor.Repos); err != nil {
+		return err
+	}
+	if len(added) > 0 {
+		repoCursors.mu.Lock()
+		repoCursors.cursors[org] = repoCursor{ListedAt: cursor.ListedAt, Repos: append(added, cursor.Repos...)}
+		repoCursors.mu.Unlock()
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// PendingDeployment is an environment a workflow run is waiting to deploy to
+type PendingDeployment struct {
+	Environment struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"environment"`
+	CurrentUserCanApprove bool `json:"current_user_can_approve"`
+}
+
+// listPendingDeployments fetches the environments a run is waiting on
+func listPendingDeployments(ctx context.Context, fullName string, runID int) ([]PendingDeployment, error) {
+	url := fmt.Sprintf("%s/repos/%s/actions/runs/%d/pending_deployments", BaseURL, fullName, runID)
+	data, err := makeRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var deployments []PendingDeployment
+	if err := json.Unmarshal(data, &deployments); err != nil {
+		return nil, err
+	}
+	return deployments, nil
+}
+
+// reviewPendingDeployments approves or rejects ("approved" or "rejected") the
+// run's deployments to the given environments
+func reviewPendingDeployments(ctx context.Context, fullName string, runID int, envIDs []int, state, comment string) error {
+	url := fmt.Sprintf("%s/repos/%s/actions/runs/%d/pending_deployments", BaseURL, fullName, runID)
+	body, err := json.Marshal(map[string]any{
+		"environment_ids": envIDs,
+		"state":           state,
+		"comment":         comment,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = makeRequest(forOperation(ctx, opReviewDeployments), "POST", url, body)
+	return err
+}
+
+// approveDeployments approves, with the -comment, every environment the
+// token may approve for each run in the repository waiting on a deployment.
+// A run waiting on several environments has them all approved in one review.
+func approveDeployments(ctx context.Context, repo Repository) Result {
+	progressf("Approving pending deployments in repository: %s\n", repo.Name)
+	result := newResult(repo, ActionDeploymentsApproved)
+
+	runs, err := listWorkflowRuns(ctx, repo.FullName, url.Values{"status": {"waiting"}})
+	if err != nil {
+		errorf("Error listing waiting runs for %s: %v\n", repo.Name, err)
+		return result.fail(err)
+	}
+
+	approved := 0
+	var firstErr error
+	for _, run := range runs {
+		deployments, err := listPendingDeployments(ctx, repo.FullName, run.ID)
+		if err != nil {
+			errorf("Error listing pending deployments for %s (Run ID: %d): %v\n", repo.Name, run.ID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		var envIDs []int
+		var envNames []string
+		for _, deployment := range deployments {
+			if deployment.CurrentUserCanApprove {
+				envIDs = append(envIDs, deployment.Environment.ID)
+				envNames = append(envNames, deployment.Environment.Name)
+			}
+		}
+		if len(envIDs) == 0 {
+			continue
+		}
+
+		if err := reviewPendingDeployments(ctx, repo.FullName, run.ID, envIDs, "approved", config.Comment); err != nil {
+			errorf("Failed to approve deployments of %s (Run ID: %d) in %s: %v\n", run.Name, run.ID, repo.Name, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		progressf("Approved deployments of %s (Run ID: %d) in %s to %s\n", run.Name, run.ID, repo.Name, strings.Join(envNames, ", "))
+		result = result.withRun(run)
+		approved++
+	}
+
+	if firstErr != nil {
+		return result.fail(firstErr)
+	}
+	if approved == 0 {
+		return result.skip("no deployments awaiting approval")
+	}
+	return result
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// dispatchWorkflow triggers a workflow_dispatch event for the workflow file on ref
+func dispatchWorkflow(ctx context.Context, fullName, file, ref string) error {
+	url := fmt.Sprintf("%s/repos/%s/actions/workflows/%s/dispatches", BaseURL, fullName, file)
+	body, err := json.Marshal(map[string]string{"ref": ref})
+	if err != nil {
+		return err
+	}
+	_, err = makeRequest(forOperation(ctx, opDispatch), "POST", url, body)
+	return err
+}
+
+// isWorkflowMissing reports whether a dispatch failed because the repository
+// has no such workflow file. GitHub answers 404, or 422 with a "not found"
+// message, rather than a distinct error code.
+func isWorkflowMissing(err error) bool {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		return false
+	}
+	switch httpErr.StatusCode {
+	case http.StatusNotFound:
+		return true
+	case http.StatusUnprocessableEntity:
+		return strings.Contains(strings.ToLower(httpErr.Message), "not found")
+	}
+	return false
+}
+
+// dispatchRepository dispatches the -dispatch workflow on the repository's
+// default branch. A repository without the workflow is reported as
+// workflow_missing, or skipped quietly with -skip-missing-workflow.
+func dispatchRepository(ctx context.Context, repo Repository) Result {
+	if reason, err := missingWorkflowReason(ctx, repo); err != nil || reason != "" {
+		return skipMissingWorkflow(repo, reason, err)
+	}
+	result := newResult(repo, ActionDispatched)
+	result.Workflow = config.Dispatch
+
+	err := dispatchWorkflow(ctx, repo.FullName, config.Dispatch, repo.DefaultBranch)
+	switch {
+	case err == nil:
+		progressf("Dispatched workflow %s in %s on %s\n", config.Dispatch, repo.Name, repo.DefaultBranch)
+		return result
+	case isWorkflowMissing(err):
+		if config.SkipMissingWorkflow {
+			return result.skip("workflow not present in repository")
+		}
+		progressf("Workflow %s not present in %s\n", config.Dispatch, repo.Name)
+		result.Action = ActionWorkflowMissing
+		result.Reason = "workflow not present in repository"
+		return result
+	default:
+		errorf("Failed to dispatch workflow %s in %s: %v\n", config.Dispatch, repo.Name, err)
+		return result.fail(err)
+	}
+}
+
+// isDispatchUnsupported reports whether a dispatch failed because the
+// workflow has no workflow_dispatch trigger, which GitHub answers with 422
+func isDispatchUnsupported(err error) bool {
+	var httpErr *HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusUnprocessableEntity &&
+		strings.Contains(httpErr.Message, "workflow_dispatch")
+}
+
+// dispatchAllWorkflows dispatches every active workflow of the repository on
+// its default branch for -dispatch-all-workflows, skipping workflows without
+// a workflow_dispatch trigger. Each workflow's outcome is printed, and the
+// repository's single result summarizes them, failing when any dispatch
+// failed, so the output and the summary count the same results.
+func dispatchAllWorkflows(ctx context.Context, repo Repository) Result {
+	result := newResult(repo, ActionDispatched)
+
+	workflows, err := listWorkflows(ctx, repo.FullName)
+	if err != nil {
+		errorf("Error listing workflows for %s: %v\n", repo.Name, err)
+		return result.fail(err)
+	}
+
+	var dispatched, unsupported int
+	var failed []string
+	var firstErr error
+	for _, workflow := range workflows {
+		if workflow.State != "active" {
+			continue
+		}
+		if ctx.Err() != nil {
+			return result.fail(ctx.Err())
+		}
+
+		err := dispatchWorkflow(ctx, repo.FullName, path.Base(workflow.Path), repo.DefaultBranch)
+		switch {
+		case err == nil:
+			progressf("Dispatched workflow %s in %s on %s\n", workflow.Path, repo.Name, repo.DefaultBranch)
+			dispatched++
+		case isDispatchUnsupported(err):
+			progressf("Skipping workflow %s in %s: no workflow_dispatch trigger\n", workflow.Path, repo.Name)
+			unsupported++
+		default:
+			errorf("Failed to dispatch workflow %s in %s: %v\n", workflow.Path, repo.Name, err)
+			failed = append(failed, workflow.Path)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		return result.fail(fmt.Errorf("%d workflow dispatches failed (%s), first: %w", len(failed), strings.Join(failed, ", "), firstErr))
+	}
+	if dispatched == 0 {
+		return result.skip("no active workflow with a workflow_dispatch trigger")
+	}
+	result.Reason = fmt.Sprintf("dispatched %d workflows, %d without a workflow_dispatch trigger", dispatched, unsupported)
+	return result
+}
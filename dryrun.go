@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// dryRunMutations counts the mutating requests -dry-run did not send
+var dryRunMutations atomic.Int64
+
+// printCostEstimate prints how many API calls the sweep since counters would
+// make for real: the lookups -dry-run sent plus the changes it skipped,
+// against the quota left on the current token
+func printCostEstimate(counters sweepCounters) {
+	lookups := requestCount.Load() - counters.apiCalls
+	changes := dryRunMutations.Load() - counters.dryRunMutations
+	estimate := lookups + changes
+
+	remaining, ok := tokens.Remaining()
+	if !ok {
+		summaryf("Estimated %d API calls (%d lookups, %d changes); remaining quota unknown\n", estimate, lookups, changes)
+		return
+	}
+	summaryf("Estimated %d API calls (%d lookups, %d changes); you have %d remaining this window\n", estimate, lookups, changes, remaining)
+	if estimate > int64(remaining) {
+		logger.Warn("estimated API calls exceed the remaining quota; the sweep would wait for the rate limit to reset", "estimate", estimate, "remaining", remaining)
+	}
+}
+
+// dryRunResponse stands in for a mutating request under -dry-run: nothing is
+// sent and the caller sees an empty response with the operation's success
+// status. With -show-requests the request
+// that would have been made is printed first.
+func dryRunResponse(req *http.Request) *http.Response {
+	if config.ShowRequests {
+		progressf("DRY RUN: %s %s\n", req.Method, redactURLCredentials(req.URL.String()))
+	}
+	dryRunMutations.Add(1)
+	status := expectedStatus(req.Context())
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode: status,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// enterpriseOrgsQuery lists one page of an enterprise's organizations. The
+// REST API has no such endpoint, so this goes through GraphQL.
+const enterpriseOrgsQuery = `query($slug: String!, $after: String) {
+  enterprise(slug: $slug) {
+    organizations(first: 100, after: $after) {
+      nodes { login }
+      pageInfo { hasNextPage endCursor }
+    }
+  }
+}`
+
+// listEnterpriseOrgs fetches the logins of every organization in the enterprise
+func listEnterpriseOrgs(ctx context.Context, slug string) ([]string, error) {
+	var orgs []string
+	var after *string
+	for {
+		body, err := json.Marshal(map[string]any{
+			"query":     enterpriseOrgsQuery,
+			"variables": map[string]any{"slug": slug, "after": after},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := makeRequest(ctx, "POST", BaseURL+"/graphql", body)
+		if err != nil {
+			return nil, err
+		}
+
+		var response struct {
+			Data struct {
+				Enterprise *struct {
+					Organizations struct {
+						Nodes []struct {
+							Login string `json:"login"`
+						} `json:"nodes"`
+						PageInfo struct {
+							HasNextPage bool   `json:"hasNextPage"`
+							EndCursor   string `json:"endCursor"`
+						} `json:"pageInfo"`
+					} `json:"organizations"`
+				} `json:"enterprise"`
+			} `json:"data"`
+			Errors []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+		if err := json.Unmarshal(data, &response); err != nil {
+			return nil, err
+		}
+		if len(response.Errors) > 0 {
+			return nil, fmt.Errorf("listing organizations of enterprise %s: %s", slug, response.Errors[0].Message)
+		}
+		if response.Data.Enterprise == nil {
+			return nil, fmt.Errorf("enterprise %s not found", slug)
+		}
+
+		page := response.Data.Enterprise.Organizations
+		for _, node := range page.Nodes {
+			orgs = append(orgs, node.Login)
+		}
+		if !page.PageInfo.HasNextPage {
+			return orgs, nil
+		}
+		cursor := page.PageInfo.EndCursor
+		after = &cursor
+	}
+}
+
+// resolveProfiles returns the organization profiles for the next sweep. With
+// -enterprise the enterprise's organizations are listed afresh each time and
+// added to any named by -org or -config.
+func resolveProfiles(ctx context.Context) ([]orgProfile, error) {
+	base := config
+	if base.Enterprise != "" {
+		orgs, err := listEnterpriseOrgs(ctx, base.Enterprise)
+		if err != nil {
+			return nil, err
+		}
+		base.Orgs = append(stringList(nil), base.Orgs...)
+		for _, org := range orgs {
+			if !containsString(base.Orgs, org) {
+				base.Orgs = append(base.Orgs, org)
+			}
+		}
+	}
+	return loadProfiles(base)
+}
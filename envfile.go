@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Environment variables read when the corresponding flags are not given,
+// typically set through -env-file during local development
+const (
+	tokenEnv = "GITHUB_TOKEN"
+	orgEnv   = "GITHUB_ORG"
+)
+
+// loadEnvFile sets the KEY=VALUE lines of the file at path as environment
+// variables. Variables already set are kept unless override is true. Blank
+// lines and # comments are ignored, an "export " prefix is allowed, and
+// values may be double-quoted (with \n, \" and \\ escapes), single-quoted
+// (taken literally) or bare, where a " #" starts a trailing comment.
+func loadEnvFile(path string, override bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, raw, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" || strings.ContainsAny(key, " \t") {
+			return fmt.Errorf("%s line %d: expected KEY=VALUE", path, lineNo)
+		}
+		value, err := parseEnvValue(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("%s line %d: %v", path, lineNo, err)
+		}
+
+		if _, set := os.LookupEnv(key); set && !override {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("%s line %d: %v", path, lineNo, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// parseEnvValue decodes the value part of an env file line
+func parseEnvValue(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		var b strings.Builder
+		for i := 1; i < len(raw); i++ {
+			switch c := raw[i]; {
+			case c == '"':
+				return b.String(), nil
+			case c == '\\' && i+1 < len(raw):
+				i++
+				switch raw[i] {
+				case 'n':
+					b.WriteByte('\n')
+				default:
+					b.WriteByte(raw[i])
+				}
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return "", fmt.Errorf("unterminated double-quoted value")
+	case strings.HasPrefix(raw, "'"):
+		end := strings.IndexByte(raw[1:], '\'')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated single-quoted value")
+		}
+		return raw[1 : end+1], nil
+	default:
+		if i := strings.Index(raw, " #"); i >= 0 {
+			raw = raw[:i]
+		}
+		return strings.TrimSpace(raw), nil
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// EventKind names what a ProgressEvent reports
+type EventKind string
+
+const (
+	EventRepoStarted EventKind = "repo_started"
+	EventRepoDone    EventKind = "repo_done"
+	EventRateLimited EventKind = "rate_limited"
+	EventRetrying    EventKind = "retrying"
+)
+
+// ProgressEvent reports one step of processing. Repo is set for repository
+// events, Action and Error when a repository is done, and WaitMS, Attempt and
+// URL when a request waits on a rate limit or is retried.
+type ProgressEvent struct {
+	Kind    EventKind `json:"kind"`
+	Time    time.Time `json:"time"`
+	Repo    string    `json:"repo,omitempty"`
+	Action  string    `json:"action,omitempty"`
+	Error   string    `json:"error,omitempty"`
+	WaitMS  int64     `json:"wait_ms,omitempty"`
+	Attempt int       `json:"attempt,omitempty"`
+	URL     string    `json:"url,omitempty"`
+}
+
+// progressEvents receives a ProgressEvent as processing proceeds, or nil to
+// send none. Events are sent from the workers and block until received, so
+// whoever sets it must keep draining it or the sweep stalls.
+var progressEvents chan<- ProgressEvent
+
+// emitEvent stamps event with the current time and sends it to
+// progressEvents, if set
+func emitEvent(event ProgressEvent) {
+	if progressEvents == nil {
+		return
+	}
+	event.Time = time.Now()
+	progressEvents <- event
+}
+
+// itemName returns the repository a worker pool item refers to
+func itemName(item any) string {
+	switch item := item.(type) {
+	case Repository:
+		return item.FullName
+	case repoRun:
+		return item.Repo.FullName
+	case planEntry:
+		return item.Repo
+	}
+	return ""
+}
+
+// stopEventLog stops the -events stream once its last event is written; it
+// does nothing when the stream is not running or already stopped
+var stopEventLog = func() {}
+
+// exit stops the -events stream, so no event is lost, and exits with code.
+// A failing exit first writes any output held by -quiet-unless-error.
+func exit(code int) {
+	stopEventLog()
+	if code != 0 {
+		releaseOutput(true)
+	}
+	os.Exit(code)
+}
+
+// startEventLog drains progressEvents for -events, writing each event to w as
+// a JSON line, until stopEventLog is called.
+func startEventLog(w io.Writer) {
+	events := make(chan ProgressEvent)
+	progressEvents = events
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		encoder := json.NewEncoder(w)
+		for event := range events {
+			encoder.Encode(event)
+		}
+	}()
+	var once sync.Once
+	stopEventLog = func() {
+		once.Do(func() {
+			progressEvents = nil
+			close(events)
+			wg.Wait()
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// writeFailuresFile writes the results that failed to path in the -runs-file
+// format, so the file can be passed straight back as -runs-file for a retry
+// pass. Failures without a selected run, such as a repository whose runs
+// could not be listed, have no run ID to retry and are written as comments.
+// Skips are not failures and are left out.
+func writeFailuresFile(path string, results []Result) error {
+	var b strings.Builder
+	for _, result := range results {
+		if result.err == nil {
+			continue
+		}
+		target := result.Org
+		if result.Repo != "" {
+			target = result.Org + "/" + result.Repo
+		}
+		if result.RunID > 0 && result.Repo != "" {
+			fmt.Fprintf(&b, "%s,%d\n", target, result.RunID)
+			continue
+		}
+		fmt.Fprintf(&b, "# %s: %s\n", target, strings.ReplaceAll(result.Error, "\n", " "))
+	}
+	return writeFileAtomic(path, []byte(b.String()))
+}
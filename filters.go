@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// createdPattern loosely matches GitHub's date qualifier syntax, such as
+// "2024-01-01", ">=2024-01-01" or "2024-01-01..2024-01-31"; anything it lets
+// through is validated by GitHub itself
+var createdPattern = regexp.MustCompile(`^(?:[<>]=?)?(?:\*|\d{4}-\d{2}-\d{2})`)
+
+// filterRepositories returns the repositories selected by the repository
+// filters, preserving their order
+func filterRepositories(repos []Repository) []Repository {
+	var selected []Repository
+	for _, repo := range repos {
+		if config.Language != "" && !strings.EqualFold(repo.Language, config.Language) {
+			continue
+		}
+		if repo.Size < config.MinSize {
+			continue
+		}
+		selected = append(selected, repo)
+	}
+
+	if len(selected) != len(repos) {
+		logger.Debug("filtered repositories", "total", len(repos), "selected", len(selected))
+	}
+	return selected
+}
+
+// capRepositories keeps the first -max-repos-per-org repositories. GitHub
+// lists an organization's repositories in a stable order, so the same
+// filters select the same sample on every sweep.
+func capRepositories(repos []Repository) []Repository {
+	if config.MaxReposPerOrg > 0 && len(repos) > config.MaxReposPerOrg {
+		logger.Debug("capped repositories", "total", len(repos), "max", config.MaxReposPerOrg)
+		return repos[:config.MaxReposPerOrg]
+	}
+	return repos
+}
+
+// runsQuery returns the query parameters that narrow a repository's runs
+// listing server-side
+func runsQuery(repo Repository) url.Values {
+	query := url.Values{}
+	if config.OnlyDefaultBranch && repo.DefaultBranch != "" {
+		query.Set("branch", repo.DefaultBranch)
+	}
+	if config.Created != "" {
+		query.Set("created", config.Created)
+	}
+	if config.ExcludePRs {
+		query.Set("exclude_pull_requests", "true")
+	}
+	if config.CheckSuiteID > 0 {
+		query.Set("check_suite_id", strconv.FormatInt(config.CheckSuiteID, 10))
+	}
+	return query
+}
+
+// defaultFailedConclusions are the run conclusions -only-failed treats as
+// failures unless -failed-conclusions says otherwise
+var defaultFailedConclusions = commaList{"failure", "cancelled", "timed_out"}
+
+// runSkipReason returns why run should not be re-triggered, or "" when it
+// passes every run filter
+func runSkipReason(run WorkflowRun) string {
+	if config.OnlyFailed && !isFailedConclusion(run.Conclusion) {
+		return fmt.Sprintf("latest run did not fail (conclusion %q)", run.Conclusion)
+	}
+	if config.MaxAttempts > 0 && run.RunAttempt >= config.MaxAttempts {
+		return fmt.Sprintf("already attempted %d times (max %d)", run.RunAttempt, config.MaxAttempts)
+	}
+	if config.SkipSelfTriggered && selfLogin != "" && strings.EqualFold(run.TriggeringActor.Login, selfLogin) {
+		return fmt.Sprintf("latest run was triggered by this tool's own account %s", selfLogin)
+	}
+	if config.SkipIfNewerThan > 0 && !run.UpdatedAt.IsZero() {
+		if age := time.Since(run.UpdatedAt); age < config.SkipIfNewerThan {
+			return fmt.Sprintf("latest run updated %s ago (within -skip-if-newer-than %s)", age.Round(time.Second), config.SkipIfNewerThan)
+		}
+	}
+	return ""
+}
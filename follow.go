@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// followPollInterval is how often -follow checks the run for progress
+const followPollInterval = 5 * time.Second
+
+// followRun waits for the attempt after previousAttempt of a re-triggered run
+// to start, reports each job as it starts and prints each job's log once the
+// job completes, returning when the run has completed
+func followRun(ctx context.Context, fullName string, runID, previousAttempt int) error {
+	ctx = withoutCache(ctx)
+	started := make(map[int]bool)
+	printed := make(map[int]bool)
+
+	for {
+		run, err := getWorkflowRun(ctx, fullName, runID)
+		if err != nil {
+			return err
+		}
+
+		if run.RunAttempt > previousAttempt {
+			jobs, err := listAttemptJobs(ctx, fullName, runID, run.RunAttempt)
+			if err != nil {
+				return err
+			}
+
+			for _, job := range jobs {
+				if job.Status != "queued" && !started[job.ID] {
+					started[job.ID] = true
+					progressf("Job started: %s\n", job.Name)
+				}
+				if job.Status != "completed" || printed[job.ID] {
+					continue
+				}
+				printed[job.ID] = true
+
+				logs, err := getJobLogs(ctx, fullName, job.ID)
+				if err != nil {
+					errorf("Error fetching logs for job %s: %v\n", job.Name, err)
+					continue
+				}
+				progressf("==> %s (%s) <==\n%s\n", job.Name, job.Conclusion, logs)
+			}
+
+			if run.Status == "completed" && len(printed) == len(jobs) {
+				progressf("Run %d attempt %d finished: %s\n", runID, run.RunAttempt, run.Conclusion)
+				return nil
+			}
+		}
+
+		if err := sleepContext(ctx, followPollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// failFastError returns the error that aborts the sweep for a failed result
+// under -fail-fast, or nil without it
+func failFastError(result Result) error {
+	if !config.FailFast {
+		return nil
+	}
+	return fmt.Errorf("%s: %w", result.Repo, result.err)
+}
+
+// processRunID re-runs the single run named by -repo and -run-id and, with
+// -follow, streams its job logs until it completes
+func processRunID(ctx context.Context, defaultOrg string) ([]Result, error) {
+	target := repoRun{Repo: repositoryFromPath(defaultOrg, config.Repo), Run: WorkflowRun{ID: config.RunID}}
+
+	if config.Follow {
+		run, err := getWorkflowRun(ctx, target.Repo.FullName, config.RunID)
+		if err != nil {
+			errorf("Error fetching run %d in %s: %v\n", config.RunID, target.Repo.FullName, err)
+			result := newResult(target.Repo, "").withRun(target.Run).fail(err)
+			emitResult(result)
+			return []Result{result}, failFastError(result)
+		}
+		target.Run = run
+	}
+
+	result := rerunTarget(ctx, target)
+	emitResult(result)
+	if result.err != nil {
+		return []Result{result}, failFastError(result)
+	}
+
+	if config.Follow {
+		if err := followRun(ctx, target.Repo.FullName, target.Run.ID, target.Run.RunAttempt); err != nil && ctx.Err() == nil {
+			errorf("Error following run %d: %v\n", target.Run.ID, err)
+		}
+	}
+	return []Result{result}, nil
+}
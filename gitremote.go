@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/url"
+	"os/exec"
+	"strings"
+)
+
+// originOrg returns the owner of the current git working tree's origin
+// remote, or "" when not inside a git repository or the remote is not GitHub
+func originOrg() string {
+	out, err := exec.Command("git", "config", "--get", "remote.origin.url").Output()
+	if err != nil {
+		return ""
+	}
+	return parseRemoteOrg(strings.TrimSpace(string(out)))
+}
+
+// parseRemoteOrg extracts the owner from a GitHub remote URL in SSH
+// (git@github.com:org/repo.git, ssh://git@github.com/org/repo) or HTTPS
+// (https://github.com/org/repo) form. It returns "" for anything else.
+func parseRemoteOrg(remote string) string {
+	var host, path string
+	if strings.Contains(remote, "://") {
+		u, err := url.Parse(remote)
+		if err != nil {
+			return ""
+		}
+		host, path = u.Hostname(), u.Path
+	} else if at := strings.Index(remote, "@"); at >= 0 {
+		hostPath := remote[at+1:]
+		colon := strings.Index(hostPath, ":")
+		if colon < 0 {
+			return ""
+		}
+		host, path = hostPath[:colon], hostPath[colon+1:]
+	} else {
+		return ""
+	}
+
+	if !strings.EqualFold(host, "github.com") {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return ""
+	}
+	return parts[0]
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// runPostHook runs command with sh after a sweep for -post-hook, passing the
+// summary JSON on stdin and its counts in RETRIGGER_* environment variables.
+// The command's output is logged line by line.
+func runPostHook(command string, summary Summary) error {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Env = append(os.Environ(),
+		"RETRIGGER_ORG="+summary.Org,
+		"RETRIGGER_TOTAL="+strconv.Itoa(summary.Total),
+		"RETRIGGER_RERUN="+strconv.Itoa(summary.Rerun),
+		"RETRIGGER_SKIPPED="+strconv.Itoa(summary.Skipped),
+		"RETRIGGER_ERRORS="+strconv.Itoa(summary.Errors),
+		"RETRIGGER_PARTIAL="+strconv.FormatBool(summary.Partial),
+	)
+	out, err := cmd.CombinedOutput()
+	for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+		if line != "" {
+			logger.Info("post-hook output", "line", line)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("post-hook %q failed: %v", command, err)
+	}
+	return nil
+}
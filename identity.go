@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// selfLogin is the login of the account behind the token, looked up at
+// startup when -skip-self-triggered needs it
+var selfLogin string
+
+// fetchAuthenticatedLogin returns the login of the account the current token
+// authenticates as
+func fetchAuthenticatedLogin(ctx context.Context) (string, error) {
+	data, err := makeRequest(ctx, "GET", fmt.Sprintf("%s/user", BaseURL), nil)
+	if err != nil {
+		return "", err
+	}
+
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := json.Unmarshal(data, &user); err != nil {
+		return "", err
+	}
+	return user.Login, nil
+}
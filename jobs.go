@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Job is one job of a workflow run attempt
+type Job struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+}
+
+// listAttemptJobs fetches the jobs of one attempt of a workflow run
+func listAttemptJobs(ctx context.Context, fullName string, runID, attempt int) ([]Job, error) {
+	url := fmt.Sprintf("%s/repos/%s/actions/runs/%d/attempts/%d/jobs?per_page=100", BaseURL, fullName, runID, attempt)
+	return paginate(ctx, url, func(data []byte) ([]Job, error) {
+		var response struct {
+			Jobs []Job `json:"jobs"`
+		}
+		err := json.Unmarshal(data, &response)
+		return response.Jobs, err
+	})
+}
+
+// getJobLogs downloads a job's plain-text log. GitHub answers with a redirect
+// to short-lived storage, which the client follows without the token.
+func getJobLogs(ctx context.Context, fullName string, jobID int) ([]byte, error) {
+	url := fmt.Sprintf("%s/repos/%s/actions/jobs/%d/logs", BaseURL, fullName, jobID)
+	return makeRequest(ctx, "GET", url, nil)
+}
+
+// jobsUnavailable reports whether a jobs lookup failed because GitHub will
+// not show the run's jobs, as it does for some run states and token
+// permissions, rather than for a transient reason
+func jobsUnavailable(err error) bool {
+	return isStatus(err, http.StatusNotFound) || isStatus(err, http.StatusForbidden)
+}
+
+// requiredJobReason returns why run should not be re-triggered under
+// -require-failed-job, or "" when the named job failed in the run's latest
+// attempt. Matrix jobs, named like "deploy (prod)", match by their base name.
+func requiredJobReason(ctx context.Context, fullName string, run WorkflowRun) (string, error) {
+	attempt := run.RunAttempt
+	if attempt < 1 {
+		attempt = 1
+	}
+	jobs, err := listAttemptJobs(ctx, fullName, run.ID, attempt)
+	if err != nil {
+		return "", err
+	}
+
+	found := false
+	for _, job := range jobs {
+		if !strings.EqualFold(job.Name, config.RequireFailedJob) && !strings.HasPrefix(strings.ToLower(job.Name), strings.ToLower(config.RequireFailedJob)+" (") {
+			continue
+		}
+		if isFailedConclusion(job.Conclusion) {
+			return "", nil
+		}
+		found = true
+	}
+	if !found {
+		return fmt.Sprintf("run has no job %q", config.RequireFailedJob), nil
+	}
+	return fmt.Sprintf("job %q did not fail", config.RequireFailedJob), nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimiter spaces requests evenly to stay under a requests-per-second cap
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// limiter is shared by every worker and every organization so -rps bounds the
+// process as a whole; it is created once in main and never per sweep
+var limiter *rateLimiter
+
+// mutationLimiter spaces mutating requests (reruns, dispatches, approvals)
+// at least -delay apart when repositories are processed concurrently
+var mutationLimiter *rateLimiter
+
+// newMutationLimiter returns the -delay limiter for mutating requests, or nil
+// when there is no delay or repositories are processed one at a time, in
+// which case runPool waits between repositories instead
+func newMutationLimiter(delay time.Duration, concurrency int) *rateLimiter {
+	if delay <= 0 || concurrency <= 1 {
+		return nil
+	}
+	return &rateLimiter{interval: delay}
+}
+
+// newRateLimiter returns a limiter allowing rps requests per second, or nil
+// (no limiting) when rps is not positive
+func newRateLimiter(rps float64) *rateLimiter {
+	if rps <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / rps)}
+}
+
+// Wait blocks until the caller may send its next request
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	stats.limiterWait.Add(int64(wait))
+	return sleepContext(ctx, wait)
+}
+
+// waitForRateLimitReset sleeps until the primary rate limit window reported
+// in resp resets, or for at most -retry-after-cap. It returns false when resp
+// carries no reset time.
+func waitForRateLimitReset(ctx context.Context, resp *http.Response) (bool, error) {
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return false, nil
+	}
+
+	wait := time.Until(time.Unix(reset, 0)) + time.Second
+	if wait < 0 {
+		wait = 0
+	}
+	if wait, err = capServerWait(wait); err != nil {
+		return false, err
+	}
+	logger.Info("rate limit exhausted, waiting for reset", "wait", wait.Round(time.Second))
+	emitEvent(ProgressEvent{Kind: EventRateLimited, WaitMS: wait.Milliseconds(), URL: resp.Request.URL.String()})
+
+	stats.rateLimitWait.Add(int64(wait))
+	stats.rateLimitWaits.Add(1)
+	return true, sleepContext(ctx, wait)
+}
+
+// quotaReserveReached reports whether the current token's remaining quota has
+// dropped to the -min-remaining reserve left for other tools sharing it
+func quotaReserveReached() bool {
+	if config.MinRemaining <= 0 {
+		return false
+	}
+	remaining, ok := tokens.Remaining()
+	return ok && remaining <= config.MinRemaining
+}
+
+// sleepContext sleeps for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
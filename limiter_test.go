@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterSharedAcrossOrganizations(t *testing.T) {
+	const rps = 50
+
+	var (
+		mu    sync.Mutex
+		times []time.Time
+		orgs  = map[string]int{}
+	)
+	useTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+
+		if org, ok := strings.CutPrefix(r.URL.Path, "/orgs/"); ok {
+			org = strings.TrimSuffix(org, "/repos")
+			mu.Lock()
+			orgs[org]++
+			mu.Unlock()
+			if r.URL.Query().Get("page") != "" {
+				w.Write([]byte("[]"))
+				return
+			}
+			fmt.Fprintf(w, `[{"name":"a","full_name":"%[1]s/a","owner":{"login":"%[1]s"}},{"name":"b","full_name":"%[1]s/b","owner":{"login":"%[1]s"}},{"name":"c","full_name":"%[1]s/c","owner":{"login":"%[1]s"}}]`, org)
+			return
+		}
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		w.Write([]byte(`{"workflow_runs":[{"id":1,"status":"completed","conclusion":"failure"}]}`))
+	}))
+	config.Concurrency = 4
+	config.ParallelOrgs = 2
+	limiter = newRateLimiter(rps)
+
+	profiles := []orgProfile{{Org: "acme"}, {Org: "globex"}}
+	results, _, err := processOrganizationsParallel(context.Background(), profiles)
+	if err != nil {
+		t.Fatalf("processOrganizationsParallel: %v", err)
+	}
+	for _, result := range results {
+		if result.Action != ActionRerun || result.err != nil {
+			t.Errorf("%s/%s: action %q, error %v; want a successful rerun", result.Org, result.Repo, result.Action, result.err)
+		}
+	}
+	if len(results) != 6 {
+		t.Errorf("%d results, want a rerun for each of the 6 repositories", len(results))
+	}
+
+	for _, org := range []string{"acme", "globex"} {
+		if orgs[org] == 0 {
+			t.Errorf("no repositories listed for %s", org)
+		}
+	}
+
+	// Together the organizations must stay under the shared -rps, with some
+	// slack for the gap between the limiter releasing a request and the
+	// server receiving it.
+	if len(times) != 16 {
+		t.Fatalf("%d requests, want two listing pages and three latest runs and reruns per organization", len(times))
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	elapsed := times[len(times)-1].Sub(times[0])
+	want := time.Duration(len(times)-1) * time.Second / rps
+	if elapsed < want*9/10 {
+		t.Errorf("%d requests took %v, want at least %v at %d requests per second", len(times), elapsed, want, rps)
+	}
+}
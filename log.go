@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// logger writes diagnostic messages to stderr
+var logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+// setupLogger configures logger, lowering the level to debug when requested
+// and raising it to errors only with -quiet
+func setupLogger(debug bool) {
+	setupLoggerOutput(debug, os.Stderr)
+}
+
+// setupLoggerOutput configures logger to write to w, redacting secrets
+func setupLoggerOutput(debug bool, w io.Writer) {
+	level := slog.LevelInfo
+	if debug {
+		level = slog.LevelDebug
+	}
+	if config.Quiet {
+		level = slog.LevelError
+	}
+	logger = slog.New(slog.NewTextHandler(redactingWriter{w}, &slog.HandlerOptions{Level: level}))
+}
+
+// progress receives human-readable progress lines. It is stdout for the text
+// output format and stderr when stdout carries structured output.
+var progress io.Writer = os.Stdout
+
+// progressf writes a formatted progress line; -quiet drops it
+func progressf(format string, args ...any) {
+	if config.Quiet {
+		return
+	}
+	io.WriteString(progress, redact(fmt.Sprintf(format, args...)))
+}
+
+// errorf writes a progress line reporting a failure, even with -quiet
+func errorf(format string, args ...any) {
+	io.WriteString(progress, redact(fmt.Sprintf(format, args...)))
+}
+
+// summaryf writes a line of an organization's or sweep's summary, even with
+// -quiet
+func summaryf(format string, args ...any) {
+	io.WriteString(progress, redact(fmt.Sprintf(format, args...)))
+}
+
+// heldOutput collects progress and log output under -quiet-unless-error
+// until the sweep shows whether anything failed; heldDest is where it goes then
+var (
+	heldOutput *lockedBuffer
+	heldDest   io.Writer
+)
+
+// lockedBuffer is a bytes.Buffer safe for concurrent writers
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// holdOutput starts holding progress and log output back for -quiet-unless-error
+func holdOutput() {
+	heldOutput, heldDest = &lockedBuffer{}, progress
+	progress = heldOutput
+	setupLoggerOutput(config.Debug, heldOutput)
+}
+
+// releaseOutput writes the held output when failed, discards it otherwise,
+// and starts holding afresh for the next sweep
+func releaseOutput(failed bool) {
+	if heldOutput == nil {
+		return
+	}
+	heldOutput.mu.Lock()
+	defer heldOutput.mu.Unlock()
+	if failed {
+		heldDest.Write(heldOutput.buf.Bytes())
+	}
+	heldOutput.buf.Reset()
+}
@@ -1,16 +1,26 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 )
 
 // GitHubToken is your GitHub Personal Access Token
 const GitHubToken = ""
 
-// Organization is the name of your GitHub organization
+// Organization is the name of your GitHub organization, used when neither
+// -org nor -config names one
 const Organization = ""
 
 // BaseURL is the base URL for the GitHub API
@@ -18,79 +28,217 @@ const BaseURL = "https://api.github.com"
 
 // Repository represents the structure of a GitHub repository
 type Repository struct {
-	Name string `json:"name"`
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	FullName string `json:"full_name"`
+	Owner    struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	DefaultBranch string `json:"default_branch"`
+	Language      string `json:"language"`
+	Size          int    `json:"size"`
 }
 
 // WorkflowRun represents a workflow run in a repository
 type WorkflowRun struct {
-	ID     int    `json:"id"`
-	Status string `json:"status"`
-	Name   string `json:"name"`
+	ID           int       `json:"id"`
+	Status       string    `json:"status"`
+	Conclusion   string    `json:"conclusion"`
+	Name         string    `json:"name"`
+	RunAttempt   int       `json:"run_attempt"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Path         string    `json:"path"`
+	CheckSuiteID int64     `json:"check_suite_id"`
+
+	TriggeringActor struct {
+		Login string `json:"login"`
+	} `json:"triggering_actor"`
+}
+
+// HTTPError is returned for a GitHub API response outside the 2xx range, or
+// outside the success statuses of the operation requested (see
+// isSuccessStatus). Message is the "message" field of GitHub's error body, when it has one.
+type HTTPError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *HTTPError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("HTTP %d: %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+	}
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// isStatus reports whether err is an HTTPError with the given status code
+func isStatus(err error, status int) bool {
+	var httpErr *HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == status
 }
 
-// AuthHeader generates the authorization header
+// AuthHeader generates the authorization header along with the headers every
+// API request carries
 func AuthHeader() map[string]string {
 	return map[string]string{
-		"Authorization": fmt.Sprintf("Bearer %s", GitHubToken),
+		"Authorization": fmt.Sprintf("Bearer %s", tokens.Current()),
 		"Accept":        "application/vnd.github.v3+json",
+		"User-Agent":    userAgent(),
+	}
+}
+
+// userAgent returns -user-agent, or a descriptive default naming this tool
+// and its version, which GitHub asks for and some GHES firewalls require
+func userAgent() string {
+	if config.UserAgent != "" {
+		return config.UserAgent
 	}
+	return "ReTriggerActions/" + version
 }
 
 // makeRequest sends an HTTP request to the GitHub API
-func makeRequest(method, url string, body []byte) ([]byte, error) {
-	client := &http.Client{}
-	req, err := http.NewRequest(method, url, nil)
-	if err != nil {
-		return nil, err
+func makeRequest(ctx context.Context, method, url string, body []byte) ([]byte, error) {
+	data, _, err := makeRequestWithHeader(ctx, method, url, body)
+	return data, err
+}
+
+// makeRequestWithHeader sends an HTTP request to the GitHub API and also
+// returns the response headers, which pagination needs for the Link header.
+// Successful GETs are served from responseCache when fetched before in the
+// same sweep. A response whose body fails to read midway (such as a
+// connection reset during a large page) is retried like a server error.
+func makeRequestWithHeader(ctx context.Context, method, url string, body []byte) ([]byte, http.Header, error) {
+	if cacheable(ctx, method) {
+		if cached, ok := responseCache.Get(url); ok {
+			return cached.data, cached.header, nil
+		}
 	}
 
-	for key, value := range AuthHeader() {
-		req.Header.Set(key, value)
+	for attempt := 0; ; attempt++ {
+		data, header, err := sendRequest(ctx, method, url, body)
+
+		var readErr *bodyReadError
+		if errors.As(err, &readErr) && ctx.Err() == nil && attempt < maxRetries(method) {
+			delay := backoffDelay(config.BackoffStrategy, attempt)
+			logger.Info("retrying request after failed body read", "method", method, "url", url, "error", readErr.err, "attempt", attempt+1, "delay", delay.Round(time.Millisecond))
+			if err := sleepContext(ctx, delay); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		if err == nil && cacheable(ctx, method) {
+			responseCache.Put(url, cachedResponse{data: data, header: header})
+		}
+		return data, header, err
 	}
+}
 
-	resp, err := client.Do(req)
+// bodyReadError reports a response whose body could not be read in full
+type bodyReadError struct {
+	err error
+}
+
+func (e *bodyReadError) Error() string {
+	return fmt.Sprintf("failed to read response body: %v", e.err)
+}
+
+func (e *bodyReadError) Unwrap() error {
+	return e.err
+}
+
+// sendRequest makes one attempt at an API request and reads its response
+func sendRequest(ctx context.Context, method, url string, body []byte) ([]byte, http.Header, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := doRequest(req)
+	if err != nil {
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	if !isSuccessStatus(ctx, resp.StatusCode) {
+		var body struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		if body.Message == "" {
+			body.Message = unexpectedStatusMessage(ctx, resp.StatusCode)
+		}
+		return nil, nil, &HTTPError{StatusCode: resp.StatusCode, Message: body.Message}
 	}
 
-	return ioutil.ReadAll(resp.Body)
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, &bodyReadError{err: err}
+	}
+	return data, resp.Header, nil
 }
 
 // getRepositories fetches all repositories in the organization
-func getRepositories() ([]Repository, error) {
+func getRepositories(ctx context.Context, org string) ([]Repository, error) {
 	var repos []Repository
-	page := 1
-	for {
-		url := fmt.Sprintf("%s/orgs/%s/repos?per_page=100&page=%d", BaseURL, Organization, page)
-		data, err := makeRequest("GET", url, nil)
-		if err != nil {
-			return nil, err
-		}
-
-		var batch []Repository
-		if err := json.Unmarshal(data, &batch); err != nil {
-			return nil, err
-		}
-		if len(batch) == 0 {
-			break
-		}
-
+	err := eachRepositoryPage(ctx, org, func(batch []Repository) error {
 		repos = append(repos, batch...)
-		page++
+		return nil
+	})
+	return repos, err
+}
+
+// eachRepositoryPage lists the organization's repositories page by page. With
+// -accessible-repos it lists the repositories the token's user can access as
+// an organization member or collaborator instead, keeping those owned by org,
+// for tokens without organization-wide read access. With -repos-cursor only
+// new repositories are fetched while the cursor is fresh, and with
+// -search-query the search API selects the repositories instead.
+func eachRepositoryPage(ctx context.Context, org string, each func([]Repository) error) error {
+	if config.SearchQuery != "" {
+		return eachSearchRepositoryPage(ctx, org, each)
+	}
+	if repoCursors != nil && !config.AccessibleRepos {
+		return eachRepositoryPageFromCursor(ctx, org, each)
+	}
+	if !config.AccessibleRepos {
+		url := fmt.Sprintf("%s/orgs/%s/repos?per_page=100", BaseURL, org)
+		return paginateEach(ctx, url, parseRepositories, each)
 	}
 
-	return repos, nil
+	url := fmt.Sprintf("%s/user/repos?affiliation=organization_member,collaborator&per_page=100", BaseURL)
+	return paginateEach(ctx, url, parseRepositories, func(batch []Repository) error {
+		var owned []Repository
+		for _, repo := range batch {
+			if strings.EqualFold(repo.Owner.Login, org) {
+				owned = append(owned, repo)
+			}
+		}
+		return each(owned)
+	})
 }
 
-// getLatestWorkflowRun fetches the latest workflow run for a repository
-func getLatestWorkflowRun(repoName string) (WorkflowRun, error) {
-	url := fmt.Sprintf("%s/repos/%s/%s/actions/runs?per_page=1", BaseURL, Organization, repoName)
-	data, err := makeRequest("GET", url, nil)
+// parseRepositories decodes one page of a repository listing
+func parseRepositories(data []byte) ([]Repository, error) {
+	var batch []Repository
+	err := json.Unmarshal(data, &batch)
+	return batch, err
+}
+
+// getLatestWorkflowRun fetches the latest workflow run for a repository,
+// narrowed server-side by the run query filters
+func getLatestWorkflowRun(ctx context.Context, repo Repository) (WorkflowRun, error) {
+	query := runsQuery(repo)
+	query.Set("per_page", "1")
+	url := fmt.Sprintf("%s?%s", runsEndpoint(repo.FullName), query.Encode())
+	data, err := makeRequest(ctx, "GET", url, nil)
 	if err != nil {
 		return WorkflowRun{}, err
 	}
@@ -103,63 +251,221 @@ func getLatestWorkflowRun(repoName string) (WorkflowRun, error) {
 	}
 
 	if len(response.WorkflowRuns) == 0 {
-		return WorkflowRun{}, fmt.Errorf("no workflow runs found for repository: %s", repoName)
+		return WorkflowRun{}, fmt.Errorf("no workflow runs found for repository: %s", repo.Name)
 	}
 
 	return response.WorkflowRuns[0], nil
 }
 
-// rerunWorkflow triggers a re-run of a workflow run
-func rerunWorkflow(repoName string, runID int) error {
-	url := fmt.Sprintf("%s/repos/%s/%s/actions/runs/%d/rerun", BaseURL, Organization, repoName, runID)
-
-	client := &http.Client{}
-	req, err := http.NewRequest("POST", url, nil)
+// getWorkflowRun fetches a single workflow run of a repository
+func getWorkflowRun(ctx context.Context, fullName string, runID int) (WorkflowRun, error) {
+	url := fmt.Sprintf("%s/repos/%s/actions/runs/%d", BaseURL, fullName, runID)
+	data, err := makeRequest(ctx, "GET", url, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create HTTP request: %v", err)
+		return WorkflowRun{}, err
 	}
 
-	for key, value := range AuthHeader() {
-		req.Header.Set(key, value)
-	}
+	var run WorkflowRun
+	err = json.Unmarshal(data, &run)
+	return run, err
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("HTTP request failed: %v", err)
+// runsEndpoint returns the URL listing a repository's runs: those of the
+// -workflow-id or -workflow-file workflow when one is selected, which GitHub
+// filters server-side, or otherwise every run
+func runsEndpoint(fullName string) string {
+	switch {
+	case config.WorkflowID > 0:
+		return fmt.Sprintf("%s/repos/%s/actions/workflows/%d/runs", BaseURL, fullName, config.WorkflowID)
+	case config.WorkflowFile != "":
+		return fmt.Sprintf("%s/repos/%s/actions/workflows/%s/runs", BaseURL, fullName, config.WorkflowFile)
+	default:
+		return fmt.Sprintf("%s/repos/%s/actions/runs", BaseURL, fullName)
 	}
-	defer resp.Body.Close()
+}
+
+// errRunScanLimit ends a run listing that reached -max-run-pages
+var errRunScanLimit = errors.New("run scan limit reached")
 
-	if resp.StatusCode != 201 {
-		responseBody, _ := ioutil.ReadAll(resp.Body)
-		return fmt.Errorf("HTTP %d: %s\nResponse Body: %s", resp.StatusCode, http.StatusText(resp.StatusCode), string(responseBody))
+// listWorkflowRuns fetches the workflow runs in a repository matching query.
+// It scans at most -max-run-pages pages so a repository with an enormous run
+// history cannot exhaust the quota; the runs found by then are returned and
+// any older match goes unreported.
+func listWorkflowRuns(ctx context.Context, fullName string, query url.Values) ([]WorkflowRun, error) {
+	query.Set("per_page", "100")
+	url := fmt.Sprintf("%s/repos/%s/actions/runs?%s", BaseURL, fullName, query.Encode())
+
+	var runs []WorkflowRun
+	pages := 0
+	err := paginateEach(ctx, url, func(data []byte) ([]WorkflowRun, error) {
+		var response struct {
+			WorkflowRuns []WorkflowRun `json:"workflow_runs"`
+		}
+		err := json.Unmarshal(data, &response)
+		return response.WorkflowRuns, err
+	}, func(batch []WorkflowRun) error {
+		runs = append(runs, batch...)
+		if pages++; config.MaxRunPages > 0 && pages >= config.MaxRunPages {
+			return errRunScanLimit
+		}
+		return nil
+	})
+	if errors.Is(err, errRunScanLimit) {
+		logger.Warn("stopped listing runs at -max-run-pages; older matches not found within scan limit", "repo", fullName, "pages", pages, "runs", len(runs))
+		return runs, nil
 	}
+	return runs, err
+}
 
-	return nil
+// rerunWorkflow triggers a re-run of a workflow run
+func rerunWorkflow(ctx context.Context, fullName string, runID int) error {
+	url := fmt.Sprintf("%s/repos/%s/actions/runs/%d/rerun", BaseURL, fullName, runID)
+	_, err := makeRequest(forOperation(ctx, opRerun), "POST", url, nil)
+	return err
 }
 
 // main orchestrates fetching repositories, workflow runs, and re-triggering them
 func main() {
-	repos, err := getRepositories()
+	parseFlags()
+	if config.Version {
+		printVersion()
+		return
+	}
+	setupLogger(config.Debug)
+
+	if err := validateConfig(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		exit(2)
+	}
+	if config.Plan != "" {
+		config.DryRun = true
+		planned = &planRecorder{}
+	}
+	if config.Output != "text" {
+		progress = os.Stderr
+	}
+	if config.QuietUnlessError {
+		holdOutput()
+	}
+	bar = newProgressBar()
+	if config.DryRun {
+		logger.Warn("dry run: no changes will be made")
+	}
+	if !config.NoWarnings {
+		for _, warning := range configWarnings(config) {
+			logger.Warn(warning)
+		}
+	}
+
+	client, err := newHTTPClient(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error configuring HTTP client: %v\n", err)
+		exit(2)
+	}
+	httpClient = client
+
+	tokenList, err := loadTokens(config)
 	if err != nil {
-		fmt.Printf("Error fetching repositories: %v\n", err)
+		errorf("Error loading tokens: %v\n", err)
+		exit(2)
+	}
+	tokens = newTokenPool(tokenList)
+	setSecrets(append([]string{config.WebhookSecret}, tokenList...))
+
+	if config.PrintConfig {
+		if err := printConfig(tokenList); err != nil {
+			fmt.Printf("Error loading config: %s\n", redact(err.Error()))
+			exit(2)
+		}
 		return
 	}
+	extra, _ := parseSuccessStatuses(config.SuccessStatuses)
+	addSuccessStatuses(extra)
+	if config.Events {
+		startEventLog(redactingWriter{os.Stderr})
+		defer stopEventLog()
+	}
+	limiter = newRateLimiter(config.RPS)
+	mutationLimiter = newMutationLimiter(config.Delay, config.Concurrency*config.ParallelOrgs)
 
-	for _, repo := range repos {
-		fmt.Printf("Processing repository: %s\n", repo.Name)
+	if config.ReposCursor != "" {
+		repoCursors, err = loadCursorFile(config.ReposCursor)
+		if err != nil {
+			fmt.Printf("Error loading repository cursor: %s\n", redact(err.Error()))
+			exit(2)
+		}
+	}
+	if config.Serve != "" && config.Cooldown <= 0 {
+		config.Cooldown = defaultServeCooldown
+	}
+	if config.Checkpoint != "" {
+		checkpointState, err = loadCheckpoint(config.Checkpoint)
+		if err != nil {
+			fmt.Printf("Error loading checkpoint: %s\n", redact(err.Error()))
+			exit(2)
+		}
+	} else if config.Cooldown > 0 {
+		checkpointState = newMemoryCheckpoint()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-		latestRun, err := getLatestWorkflowRun(repo.Name)
+	if config.SkipSelfTriggered {
+		selfLogin, err = fetchAuthenticatedLogin(ctx)
 		if err != nil {
-			fmt.Printf("Error fetching latest workflow run for %s: %v\n", repo.Name, err)
-			continue
+			fmt.Printf("Error identifying the token's account for -skip-self-triggered: %s\n", redact(err.Error()))
+			exit(2)
 		}
+		logger.Debug("authenticated", "login", selfLogin)
+	}
 
-		fmt.Printf("Re-running workflow: %s (Run ID: %d)\n", latestRun.Name, latestRun.ID)
-		err = rerunWorkflow(repo.Name, latestRun.ID)
+	if config.ListOrgs {
+		if err := listOrgs(ctx); err != nil {
+			fmt.Printf("Error listing organizations: %s\n", redact(err.Error()))
+			exit(1)
+		}
+		return
+	}
+
+	if config.Serve != "" {
+		if err := serveWebhooks(ctx, config.Serve); err != nil {
+			fmt.Printf("Error serving webhooks: %s\n", redact(err.Error()))
+			exit(1)
+		}
+		return
+	}
+
+	for {
+		profiles, err := resolveProfiles(ctx)
 		if err != nil {
-			fmt.Printf("Failed to re-run workflow for %s: %v\n", repo.Name, err)
-		} else {
-			fmt.Printf("Successfully re-ran workflow for %s\n", repo.Name)
+			fmt.Printf("Error loading config: %s\n", redact(err.Error()))
+			exit(2)
 		}
+
+		if config.Count {
+			countMatches(ctx, profiles)
+			return
+		}
+
+		if err := runSweep(ctx, profiles); err != nil {
+			fmt.Printf("Aborting: %s\n", redact(err.Error()))
+			exit(1)
+		}
+
+		if config.Interval <= 0 {
+			if unhealthy {
+				exit(1)
+			}
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(config.Interval):
+		}
+
+		progressf("==== %s ====\n", time.Now().Format(time.RFC3339))
 	}
 }
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// mainArgsEnv, when set, makes the test binary run main with these
+// newline-separated arguments instead of the tests (see runMain)
+const mainArgsEnv = "ACTIONS_TEST_MAIN_ARGS"
+
+func TestMain(m *testing.M) {
+	if args, ok := os.LookupEnv(mainArgsEnv); ok {
+		os.Args = append([]string{"actions"}, strings.Split(args, "\n")...)
+		flag.CommandLine = flag.NewFlagSet("actions", flag.ExitOnError)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runMain runs the tool as a separate process with args and returns its
+// combined output and exit code
+func runMain(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), mainArgsEnv+"="+strings.Join(args, "\n"), tokenEnv+"="+testToken)
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatalf("running main: %v", err)
+	}
+	return string(out), cmd.ProcessState.ExitCode()
+}
+
+// testToken is the token the test globals authenticate with
+const testToken = "test-token-0123456789"
+
+// rewriteTransport sends every request to target, whatever its host, so the
+// code under test keeps building URLs from BaseURL
+type rewriteTransport struct {
+	target *url.URL
+	next   http.RoundTripper
+}
+
+func (t rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = t.target.Scheme, t.target.Host
+	return t.next.RoundTrip(req)
+}
+
+// resetGlobals gives the test default globals, with output discarded, the
+// test token loaded and millisecond retry delays, and restores the previous
+// ones when it ends
+func resetGlobals(t *testing.T) {
+	t.Helper()
+	savedConfig, savedClient, savedProgress, savedLogger := config, httpClient, progress, logger
+	savedTokens, savedLimiter, savedMutationLimiter := tokens, limiter, mutationLimiter
+	savedBaseDelay := retryBaseDelay
+	t.Cleanup(func() {
+		config, httpClient, progress, logger = savedConfig, savedClient, savedProgress, savedLogger
+		tokens, limiter, mutationLimiter = savedTokens, savedLimiter, savedMutationLimiter
+		retryBaseDelay = savedBaseDelay
+		responseCache.Clear()
+		triggeredRuns = nil
+	})
+
+	config = Config{}
+	progress = io.Discard
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	tokens = newTokenPool([]string{testToken})
+	limiter, mutationLimiter = nil, nil
+	retryBaseDelay = time.Millisecond
+	responseCache.Clear()
+}
+
+// useTestServer resets the globals and points the shared client at a server
+// running handler
+func useTestServer(t *testing.T, handler http.Handler) *httptest.Server {
+	t.Helper()
+	resetGlobals(t)
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpClient = &http.Client{Transport: rewriteTransport{target: target, next: server.Client().Transport}}
+	return server
+}
+
+// requestCounter counts the requests a test server receives by method and
+// path
+type requestCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (c *requestCounter) add(r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[string]int)
+	}
+	c.counts[r.Method+" "+r.URL.Path]++
+}
+
+func (c *requestCounter) get(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts[key]
+}
+
+func TestRetryAfterBodyCutOff(t *testing.T) {
+	var counter requestCounter
+	useTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		counter.add(r)
+		if counter.get("GET /items") == 1 {
+			// Promise more than is sent, so the server closes the
+			// connection partway through the body
+			w.Header().Set("Content-Length", "100")
+			w.Write([]byte(`[1,2,`))
+			return
+		}
+		w.Write([]byte(`[1,2,3]`))
+	}))
+	config.RetryGet = 1
+
+	data, err := makeRequest(context.Background(), "GET", BaseURL+"/items", nil)
+	if err != nil {
+		t.Fatalf("makeRequest: %v", err)
+	}
+	if string(data) != "[1,2,3]" {
+		t.Errorf("body = %q, want the retried response", data)
+	}
+	if n := counter.get("GET /items"); n != 2 {
+		t.Errorf("%d requests, want 2", n)
+	}
+}
+
+func TestBodyCutOffExhaustsRetries(t *testing.T) {
+	var counter requestCounter
+	useTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		counter.add(r)
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte(`[1,2,`))
+	}))
+	config.RetryGet = 2
+
+	_, err := makeRequest(context.Background(), "GET", BaseURL+"/items", nil)
+	var readErr *bodyReadError
+	if !errors.As(err, &readErr) {
+		t.Fatalf("makeRequest error = %v, want a bodyReadError", err)
+	}
+	if n := counter.get("GET /items"); n != 3 {
+		t.Errorf("%d requests, want 3", n)
+	}
+}
+
+func TestRerunSendsSameHeadersAsGet(t *testing.T) {
+	headers := map[string]http.Header{}
+	var mu sync.Mutex
+	useTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		headers[r.Method] = r.Header.Clone()
+		mu.Unlock()
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		w.Write([]byte(`{"workflow_runs":[{"id":11}]}`))
+	}))
+	config.UserAgent = "test-agent"
+
+	ctx := context.Background()
+	if _, err := getLatestWorkflowRun(ctx, repositoryFromPath("acme", "r1")); err != nil {
+		t.Fatalf("getLatestWorkflowRun: %v", err)
+	}
+	if err := rerunWorkflow(ctx, "acme/r1", 11); err != nil {
+		t.Fatalf("rerunWorkflow: %v", err)
+	}
+
+	want := map[string]string{
+		"Authorization": "Bearer " + testToken,
+		"Accept":        "application/vnd.github.v3+json",
+		"User-Agent":    "test-agent",
+	}
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		for key, value := range want {
+			if got := headers[method].Get(key); got != value {
+				t.Errorf("%s %s header = %q, want %q", method, key, got, value)
+			}
+		}
+	}
+}
+
+func TestUnreachableServer(t *testing.T) {
+	server := useTestServer(t, http.NotFoundHandler())
+	server.Close()
+	config.RetryGet = 1
+
+	_, err := makeRequest(context.Background(), "GET", BaseURL+"/items", nil)
+	if err == nil {
+		t.Fatal("makeRequest succeeded against a closed server")
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Errorf("error = %v, want connection refused", err)
+	}
+	if want := "could not reach api.github.com: check network/VPN"; !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("error = %q, want it to start with %q", err, want)
+	}
+}
+
+func TestInvalidCACertExits(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	if err := os.WriteFile(empty, []byte("not a certificate\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, caCert := range []string{missing, empty} {
+		out, code := runMain(t, "-org", "acme", "-ca-cert", caCert)
+		if code != 2 {
+			t.Errorf("-ca-cert %s: exit status %d, want 2; output:\n%s", caCert, code, out)
+		}
+		if !strings.Contains(out, "Error configuring HTTP client") {
+			t.Errorf("-ca-cert %s: output %q does not report the client error", caCert, out)
+		}
+	}
+}
+
+func TestClientCertErrorsExit(t *testing.T) {
+	cert := filepath.Join(t.TempDir(), "client.pem")
+	if err := os.WriteFile(cert, []byte("not a certificate\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-client-cert", cert}, "-client-cert and -client-key must be provided together"},
+		{[]string{"-client-key", cert}, "-client-cert and -client-key must be provided together"},
+		{[]string{"-client-cert", cert, "-client-key", cert}, "failed to load client certificate"},
+	}
+	for _, tt := range tests {
+		out, code := runMain(t, append([]string{"-org", "acme"}, tt.args...)...)
+		if code != 2 || !strings.Contains(out, tt.want) {
+			t.Errorf("%v: exit status %d, output %q; want status 2 reporting %q", tt.args, code, out, tt.want)
+		}
+	}
+}
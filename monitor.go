@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"strings"
+)
+
+// unhealthy records that a -monitor sweep found a repository whose latest run
+// failed, so the process exits with status 1
+var unhealthy bool
+
+// monitorRepository reports the conclusion of the repository's latest run for
+// -monitor without re-running anything. The run is always fetched from the
+// API, since -monitor with -interval polls the same runs for changes. With
+// -codeowners a failing result names the owners of the workflow file.
+func monitorRepository(ctx context.Context, repo Repository) Result {
+	if reason, err := missingWorkflowReason(ctx, repo); err != nil || reason != "" {
+		return skipMissingWorkflow(repo, reason, err)
+	}
+	run, err := getLatestWorkflowRun(withoutCache(ctx), repo)
+	if err != nil {
+		errorf("Error fetching latest workflow run for %s: %v\n", repo.Name, err)
+		return newResult(repo, "").fail(err)
+	}
+
+	result := newResult(repo, ActionHealthy).withRun(run)
+	switch {
+	case run.Conclusion == "":
+		result.Reason = "latest run still in progress"
+	case isFailedConclusion(run.Conclusion):
+		result.Action = ActionFailing
+		if config.Codeowners {
+			owners, err := fileOwners(ctx, repo, run.Path)
+			if err != nil {
+				logger.Warn("could not read CODEOWNERS", "repo", repo.FullName, "error", err)
+			}
+			result.Owners = strings.Join(owners, " ")
+		}
+		if result.Owners != "" {
+			progressf("%s: latest run of %s concluded %s (owners: %s)\n", repo.FullName, run.Name, run.Conclusion, result.Owners)
+		} else {
+			progressf("%s: latest run of %s concluded %s\n", repo.FullName, run.Name, run.Conclusion)
+		}
+	}
+	return result
+}
+
+// reportHealth prints how many repositories' latest runs failed in a -monitor
+// sweep and marks the invocation unhealthy when any did, or healthy again
+// when none did
+func reportHealth(results []Result) {
+	failing, checked := 0, 0
+	for _, result := range results {
+		switch result.Action {
+		case ActionFailing:
+			failing++
+			checked++
+		case ActionHealthy:
+			checked++
+		}
+	}
+	unhealthy = failing > 0
+	if !unhealthy {
+		summaryf("Healthy: no latest run failed\n")
+		return
+	}
+	summaryf("Unhealthy: latest run failed in %d of %d repositories\n", failing, checked)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Mutating operations, each with its success statuses in successStatuses
+const (
+	opRerun             = "rerun"
+	opDispatch          = "dispatch"
+	opApprove           = "approve"
+	opEnableWorkflow    = "enable-workflow"
+	opDisableWorkflow   = "disable-workflow"
+	opReviewDeployments = "review-deployments"
+	opDeleteArtifact    = "delete-artifact"
+)
+
+// successStatuses lists the statuses GitHub answers each operation with on
+// success. -success-status adds to them for servers that differ.
+var successStatuses = map[string][]int{
+	opRerun:             {http.StatusCreated},
+	opDispatch:          {http.StatusNoContent},
+	opApprove:           {http.StatusCreated, http.StatusNoContent},
+	opEnableWorkflow:    {http.StatusNoContent},
+	opDisableWorkflow:   {http.StatusNoContent},
+	opReviewDeployments: {http.StatusOK},
+	opDeleteArtifact:    {http.StatusNoContent},
+}
+
+// operationKey marks a context whose requests perform a mutating operation
+type operationKey struct{}
+
+// forOperation returns a context whose requests are checked against op's
+// success statuses
+func forOperation(ctx context.Context, op string) context.Context {
+	return context.WithValue(ctx, operationKey{}, op)
+}
+
+// isMutation reports whether a request made with ctx changes state on GitHub.
+// Mutations are the requests made for an operation; the method alone does not
+// tell, since GraphQL queries are read-only POSTs.
+func isMutation(ctx context.Context) bool {
+	return ctx.Value(operationKey{}) != nil
+}
+
+// isSuccessStatus reports whether status answers a request made with ctx
+// successfully. A request for an operation succeeds only with one of the
+// operation's success statuses, so a server that answers differently is
+// noticed rather than assumed to have done the work; -success-status accepts
+// more. Requests for no operation succeed with any 2xx status.
+func isSuccessStatus(ctx context.Context, status int) bool {
+	op, _ := ctx.Value(operationKey{}).(string)
+	if expected, known := successStatuses[op]; known {
+		return slices.Contains(expected, status)
+	}
+	return status >= 200 && status < 300
+}
+
+// unexpectedStatusMessage explains a 2xx status that isSuccessStatus refused
+// for the operation of ctx, or returns "" for any other status
+func unexpectedStatusMessage(ctx context.Context, status int) string {
+	op, _ := ctx.Value(operationKey{}).(string)
+	if status < 200 || status >= 300 {
+		return ""
+	}
+	return fmt.Sprintf("unexpected status for %s, which succeeds with %v; accept it with -success-status %s=%d", op, successStatuses[op], op, status)
+}
+
+// expectedStatus returns the status a request made with ctx answers on
+// success, which -dry-run responds with in its place
+func expectedStatus(ctx context.Context) int {
+	op, _ := ctx.Value(operationKey{}).(string)
+	if expected := successStatuses[op]; len(expected) > 0 {
+		return expected[0]
+	}
+	return http.StatusNoContent
+}
+
+// parseSuccessStatuses parses -success-status values of the form
+// "operation=status[,status...]"
+func parseSuccessStatuses(values []string) (map[string][]int, error) {
+	parsed := make(map[string][]int)
+	for _, value := range values {
+		op, list, found := strings.Cut(value, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid -success-status %q: expected operation=status", value)
+		}
+		if _, known := successStatuses[op]; !known {
+			return nil, fmt.Errorf("invalid -success-status %q: unknown operation %q", value, op)
+		}
+		for _, field := range strings.Split(list, ",") {
+			status, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil || status < 100 || status > 599 {
+				return nil, fmt.Errorf("invalid -success-status %q: bad status %q", value, field)
+			}
+			parsed[op] = append(parsed[op], status)
+		}
+	}
+	return parsed, nil
+}
+
+// addSuccessStatuses adds the parsed -success-status values to the table
+func addSuccessStatuses(extra map[string][]int) {
+	for op, statuses := range extra {
+		successStatuses[op] = append(successStatuses[op], statuses...)
+	}
+}
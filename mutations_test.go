@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// statusServer answers every request with the status in *status
+func statusServer(t *testing.T, status *int) {
+	t.Helper()
+	useTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(*status)
+	}))
+}
+
+func TestOperationSuccessStatuses(t *testing.T) {
+	var ops []string
+	for op := range successStatuses {
+		ops = append(ops, op)
+	}
+	slices.Sort(ops)
+
+	for _, op := range ops {
+		t.Run(op, func(t *testing.T) {
+			var status int
+			statusServer(t, &status)
+			ctx := forOperation(context.Background(), op)
+
+			for _, status = range successStatuses[op] {
+				if _, err := makeRequest(ctx, "POST", BaseURL+"/op", nil); err != nil {
+					t.Errorf("status %d: %v, want success", status, err)
+				}
+			}
+
+			for _, status = range []int{http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusNoContent} {
+				if slices.Contains(successStatuses[op], status) {
+					continue
+				}
+				_, err := makeRequest(ctx, "POST", BaseURL+"/op", nil)
+				if !isStatus(err, status) {
+					t.Errorf("status %d: error %v, want HTTP %d", status, err, status)
+					continue
+				}
+				if want := fmt.Sprintf("-success-status %s=%d", op, status); !strings.Contains(err.Error(), want) {
+					t.Errorf("status %d: error %q does not suggest %s", status, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRequestWithoutOperationAcceptsAny2xx(t *testing.T) {
+	var status int
+	statusServer(t, &status)
+
+	for _, status = range []int{http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusNoContent} {
+		if _, err := makeRequest(context.Background(), "POST", BaseURL+"/graphql", nil); err != nil {
+			t.Errorf("status %d: %v, want success", status, err)
+		}
+	}
+}
+
+func TestAddSuccessStatuses(t *testing.T) {
+	saved := maps.Clone(successStatuses)
+	t.Cleanup(func() { successStatuses = saved })
+	successStatuses = maps.Clone(saved)
+
+	extra, err := parseSuccessStatuses([]string{"rerun=200, 202"})
+	if err != nil {
+		t.Fatalf("parseSuccessStatuses: %v", err)
+	}
+	addSuccessStatuses(extra)
+
+	status := http.StatusAccepted
+	statusServer(t, &status)
+	if err := rerunWorkflow(context.Background(), "acme/r1", 11); err != nil {
+		t.Errorf("rerun answered with 202 after -success-status rerun=202: %v", err)
+	}
+}
+
+func TestParseSuccessStatusesErrors(t *testing.T) {
+	for _, value := range []string{"rerun", "unknown=200", "rerun=ok", "rerun=99", "rerun=600", "rerun="} {
+		if _, err := parseSuccessStatuses([]string{value}); err == nil {
+			t.Errorf("parseSuccessStatuses(%q) succeeded, want an error", value)
+		}
+	}
+}
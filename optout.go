@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// ignoreFileName is the file a repository adds at its root to opt out of
+// org-wide reruns
+const ignoreFileName = ".retrigger-ignore"
+
+// ignoreFileCache remembers which repositories have the ignore file, keyed by
+// full name, for the rest of the sweep. It is cleared with responseCache so
+// adding or removing the file takes effect on the next sweep.
+var ignoreFileCache sync.Map
+
+// hasIgnoreFile reports whether the repository contains the ignore file at its
+// root. A 404 is the common answer and is cached like any other.
+func hasIgnoreFile(ctx context.Context, repo Repository) (bool, error) {
+	if cached, ok := ignoreFileCache.Load(repo.FullName); ok {
+		return cached.(bool), nil
+	}
+
+	url := fmt.Sprintf("%s/repos/%s/contents/%s", BaseURL, repo.FullName, ignoreFileName)
+	_, err := makeRequest(ctx, "GET", url, nil)
+	switch {
+	case err == nil:
+		ignoreFileCache.Store(repo.FullName, true)
+		return true, nil
+	case isStatus(err, http.StatusNotFound):
+		ignoreFileCache.Store(repo.FullName, false)
+		return false, nil
+	default:
+		return false, err
+	}
+}
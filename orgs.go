@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// OrgInfo is an organization as returned by the organization listing endpoints
+type OrgInfo struct {
+	Login string `json:"login"`
+	Repos int    `json:"repos,omitempty"`
+}
+
+// listUserOrgs fetches every organization the token's user belongs to
+func listUserOrgs(ctx context.Context) ([]OrgInfo, error) {
+	url := fmt.Sprintf("%s/user/orgs?per_page=100", BaseURL)
+	return paginate(ctx, url, func(data []byte) ([]OrgInfo, error) {
+		var batch []OrgInfo
+		err := json.Unmarshal(data, &batch)
+		return batch, err
+	})
+}
+
+// countOrgRepos returns the number of public and private repositories in org
+// visible to the token
+func countOrgRepos(ctx context.Context, org string) (int, error) {
+	data, err := makeRequest(ctx, "GET", fmt.Sprintf("%s/orgs/%s", BaseURL, org), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var details struct {
+		PublicRepos       int `json:"public_repos"`
+		TotalPrivateRepos int `json:"total_private_repos"`
+	}
+	if err := json.Unmarshal(data, &details); err != nil {
+		return 0, err
+	}
+	return details.PublicRepos + details.TotalPrivateRepos, nil
+}
+
+// listOrgs prints the organizations the token can access, with repository
+// counts when -with-repo-counts is set. It makes no mutating calls.
+func listOrgs(ctx context.Context) error {
+	orgs, err := listUserOrgs(ctx)
+	if err != nil {
+		return err
+	}
+
+	if config.WithRepoCounts {
+		for i := range orgs {
+			count, err := countOrgRepos(ctx, orgs[i].Login)
+			if err != nil {
+				return fmt.Errorf("counting repositories in %s: %v", orgs[i].Login, err)
+			}
+			orgs[i].Repos = count
+		}
+	}
+
+	if config.Output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if orgs == nil {
+			orgs = []OrgInfo{}
+		}
+		return enc.Encode(orgs)
+	}
+
+	for _, org := range orgs {
+		if config.WithRepoCounts {
+			fmt.Printf("%s\t%d repositories\n", org.Login, org.Repos)
+		} else {
+			fmt.Println(org.Login)
+		}
+	}
+	return nil
+}
+
+// orgActionsPermissions is the organization's GitHub Actions policy
+type orgActionsPermissions struct {
+	EnabledRepositories string `json:"enabled_repositories"`
+	AllowedActions      string `json:"allowed_actions"`
+}
+
+// checkOrgPermissions reads the organization's Actions policy and warns when
+// it will make reruns fail: Actions disabled for every repository, or enabled
+// only for selected ones. Reading the policy needs organization admin rights,
+// so a failure to read it is only logged.
+func checkOrgPermissions(ctx context.Context, org string) {
+	data, err := makeRequest(ctx, "GET", fmt.Sprintf("%s/orgs/%s/actions/permissions", BaseURL, org), nil)
+	if err != nil {
+		logger.Warn("could not read organization Actions permissions", "org", org, "error", err)
+		return
+	}
+
+	var permissions orgActionsPermissions
+	if err := json.Unmarshal(data, &permissions); err != nil {
+		logger.Warn("could not read organization Actions permissions", "org", org, "error", err)
+		return
+	}
+
+	switch permissions.EnabledRepositories {
+	case "none":
+		logger.Warn("organization Actions are disabled; reruns will fail", "org", org)
+	case "selected":
+		logger.Warn("organization Actions are enabled only for selected repositories; reruns in others will fail", "org", org)
+	}
+	if permissions.AllowedActions != "" && permissions.AllowedActions != "all" {
+		logger.Info("organization restricts which actions may run", "org", org, "allowed_actions", permissions.AllowedActions)
+	}
+}
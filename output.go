@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Action values recorded in Result.Action
+const (
+	ActionRerun               = "rerun"
+	ActionSkipped             = "skipped"
+	ActionError               = "error"
+	ActionTimedOut            = "timed_out"
+	ActionArtifactsDeleted    = "artifacts_deleted"
+	ActionWorkflowEnabled     = "workflow_enabled"
+	ActionWorkflowDisabled    = "workflow_disabled"
+	ActionApproved            = "approved"
+	ActionRunnersChecked      = "runners_checked"
+	ActionRunnersOffline      = "runners_offline"
+	ActionDispatched          = "dispatched"
+	ActionWorkflowMissing     = "workflow_missing"
+	ActionDeploymentsApproved = "deployments_approved"
+	ActionWorkflowListed      = "workflow_listed"
+	ActionHealthy             = "healthy"
+	ActionFailing             = "failing"
+)
+
+// Result records the outcome of processing a single repository
+type Result struct {
+	Org          string `json:"org"`
+	Repo         string `json:"repo"`
+	Action       string `json:"action"`
+	Workflow     string `json:"workflow,omitempty"`
+	WorkflowPath string `json:"workflow_path,omitempty"`
+	RunID        int    `json:"run_id,omitempty"`
+	CheckSuiteID int64  `json:"check_suite_id,omitempty"`
+	Owners       string `json:"owners,omitempty"`
+	Conclusion   string `json:"conclusion,omitempty"`
+	RerunReason  string `json:"rerun_reason,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+	Error        string `json:"error,omitempty"`
+	DurationMS   int64  `json:"duration_ms,omitempty"`
+
+	err error
+}
+
+// newResult starts a Result for repo with the given action, annotated with
+// the -reason for the sweep
+func newResult(repo Repository, action string) Result {
+	return Result{Org: repo.Owner.Login, Repo: repo.Name, Action: action, RerunReason: config.Reason}
+}
+
+// withRun records run as the workflow run the result refers to
+func (r Result) withRun(run WorkflowRun) Result {
+	r.Workflow = run.Name
+	r.RunID = run.ID
+	r.Conclusion = run.Conclusion
+	r.WorkflowPath = run.Path
+	r.CheckSuiteID = run.CheckSuiteID
+	return r
+}
+
+// skip marks the result as intentionally skipped for reason
+func (r Result) skip(reason string) Result {
+	r.Action = ActionSkipped
+	r.Reason = reason
+	return r
+}
+
+// fail marks the result as an error caused by err
+func (r Result) fail(err error) Result {
+	r.Action = ActionError
+	r.Error = redact(err.Error())
+	r.err = err
+	return r
+}
+
+// writeFileAtomic writes data to path through a temporary file in the same
+// directory followed by a rename, so readers never see a partial file
+func writeFileAtomic(path string, data []byte) error {
+	f, err := createAtomicFile(path)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.File.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	return f.Close()
+}
+
+// atomicFile is a temporary file that replaces path when closed
+type atomicFile struct {
+	*os.File
+	path string
+}
+
+// createAtomicFile starts writing path through a temporary file in the same
+// directory, creating the directory if needed
+func createAtomicFile(path string) (*atomicFile, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	return &atomicFile{File: tmp, path: path}, nil
+}
+
+// Close renames the temporary file over path
+func (f *atomicFile) Close() error {
+	if err := f.File.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), f.path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
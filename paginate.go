@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+)
+
+// linkNextPattern extracts the rel="next" target from a Link header
+var linkNextPattern = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// maxPages bounds a single listing. At 100 items per page it is far beyond
+// any real organization, so reaching it means the server never stops handing
+// out pages.
+const maxPages = 10000
+
+// paginate fetches every page of a list endpoint starting at pageURL and
+// returns the concatenated items. On failure the items from the pages fetched
+// so far are returned along with the error.
+func paginate[T any](ctx context.Context, pageURL string, unmarshal func([]byte) ([]T, error)) ([]T, error) {
+	var items []T
+	err := paginateEach(ctx, pageURL, unmarshal, func(batch []T) error {
+		items = append(items, batch...)
+		return nil
+	})
+	return items, err
+}
+
+// paginateEach fetches every page of a list endpoint starting at pageURL and
+// passes each page's items to each as it arrives, stopping at the first error
+// from each. It follows the Link header when GitHub sends one and otherwise
+// increments the page query parameter until an empty page. Each page counts
+// against the request budget. A listing that runs past maxPages, or whose
+// page repeats the previous one exactly, fails instead of looping forever
+// against a server or proxy that ignores the page parameter.
+func paginateEach[T any](ctx context.Context, pageURL string, unmarshal func([]byte) ([]T, error), each func([]T) error) error {
+	next := pageURL
+	var previous []byte
+	for pages := 0; next != ""; pages++ {
+		if err := checkRequestBudget(); err != nil {
+			return err
+		}
+		if pages >= maxPages {
+			return fmt.Errorf("listing %s exceeded %d pages; the server is not advancing pagination", pageURL, maxPages)
+		}
+
+		data, header, err := makeRequestWithHeader(ctx, "GET", next, nil)
+		if err != nil {
+			return err
+		}
+		if previous != nil && bytes.Equal(data, previous) {
+			return fmt.Errorf("page %s repeats the previous page; the server is not advancing pagination", next)
+		}
+		previous = data
+
+		batch, err := unmarshal(data)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		if err := each(batch); err != nil {
+			return err
+		}
+
+		next, err = nextPageURL(next, header)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nextPageURL returns the URL of the page after current. Without a Link
+// header it falls back to incrementing the page parameter; with a Link header
+// that has no rel="next" it returns "" because current was the last page.
+func nextPageURL(current string, header http.Header) (string, error) {
+	if link := header.Get("Link"); link != "" {
+		if m := linkNextPattern.FindStringSubmatch(link); m != nil {
+			return m[1], nil
+		}
+		return "", nil
+	}
+
+	u, err := url.Parse(current)
+	if err != nil {
+		return "", fmt.Errorf("invalid page URL %q: %v", current, err)
+	}
+	q := u.Query()
+	page, _ := strconv.Atoi(q.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	q.Set("page", strconv.Itoa(page+1))
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
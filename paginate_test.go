@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testPage is one page served by a paginate test server
+type testPage struct {
+	status int
+	body   string
+	link   string
+}
+
+// servePages answers /items with the page named by the page query parameter,
+// defaulting to page 1, and 404 for pages it does not have
+func servePages(t *testing.T, pages map[string]testPage, counter *requestCounter) {
+	t.Helper()
+	useTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		counter.add(r)
+		name := r.URL.Query().Get("page")
+		if name == "" {
+			name = "1"
+		}
+		page, ok := pages[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if page.link != "" {
+			w.Header().Set("Link", page.link)
+		}
+		if page.status != 0 {
+			w.WriteHeader(page.status)
+		}
+		w.Write([]byte(page.body))
+	}))
+}
+
+func parseInts(data []byte) ([]int, error) {
+	var items []int
+	err := json.Unmarshal(data, &items)
+	return items, err
+}
+
+func TestPaginate(t *testing.T) {
+	tests := []struct {
+		name     string
+		pages    map[string]testPage
+		want     []int
+		requests int
+		wantErr  bool
+	}{
+		{
+			name: "page parameter until empty page",
+			pages: map[string]testPage{
+				"1": {body: "[1,2]"},
+				"2": {body: "[3]"},
+				"3": {body: "[]"},
+			},
+			want:     []int{1, 2, 3},
+			requests: 3,
+		},
+		{
+			name: "link header until no next",
+			pages: map[string]testPage{
+				"1": {body: "[1,2]", link: `<` + BaseURL + `/items?page=2>; rel="next"`},
+				"2": {body: "[3]", link: `<` + BaseURL + `/items?page=1>; rel="prev"`},
+			},
+			want:     []int{1, 2, 3},
+			requests: 2,
+		},
+		{
+			name: "first page empty",
+			pages: map[string]testPage{
+				"1": {body: "[]"},
+			},
+			requests: 1,
+		},
+		{
+			name: "failed page keeps earlier items",
+			pages: map[string]testPage{
+				"1": {body: "[1,2]"},
+				"2": {status: http.StatusInternalServerError, body: `{"message":"boom"}`},
+			},
+			want:     []int{1, 2},
+			requests: 2,
+			wantErr:  true,
+		},
+		{
+			name: "invalid page",
+			pages: map[string]testPage{
+				"1": {body: "[1,2]"},
+				"2": {body: "not json"},
+			},
+			want:     []int{1, 2},
+			requests: 2,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var counter requestCounter
+			servePages(t, tt.pages, &counter)
+
+			got, err := paginate(context.Background(), BaseURL+"/items", parseInts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("paginate error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("paginate = %v, want %v", got, tt.want)
+			}
+			if n := counter.get("GET /items"); n != tt.requests {
+				t.Errorf("%d requests, want %d", n, tt.requests)
+			}
+		})
+	}
+}
+
+func TestPaginateRepeatedPage(t *testing.T) {
+	var counter requestCounter
+	useTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		counter.add(r)
+		w.Write([]byte("[1,2]"))
+	}))
+
+	got, err := paginate(context.Background(), BaseURL+"/items", parseInts)
+	if err == nil || !strings.Contains(err.Error(), "repeats the previous page") {
+		t.Fatalf("paginate error = %v, want a repeated page error", err)
+	}
+	if !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("paginate = %v, want the first page only", got)
+	}
+	if n := counter.get("GET /items"); n != 2 {
+		t.Errorf("%d requests, want 2", n)
+	}
+}
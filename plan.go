@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// planEntry is one rerun recorded by -plan, with the run as it was then so
+// -apply can tell whether it changed since
+type planEntry struct {
+	Repo       string    `json:"repo"`
+	RunID      int       `json:"run_id"`
+	Workflow   string    `json:"workflow,omitempty"`
+	RunAttempt int       `json:"run_attempt"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// planFile is the document -plan writes and -apply executes. Checksum is the
+// SHA-256 of the JSON encoding of Actions, so a plan edited after review is
+// refused.
+type planFile struct {
+	CreatedAt time.Time   `json:"created_at"`
+	Checksum  string      `json:"checksum"`
+	Actions   []planEntry `json:"actions"`
+}
+
+// planRecorder collects the reruns a -plan sweep would make
+type planRecorder struct {
+	mu      sync.Mutex
+	entries []planEntry
+}
+
+// planned records the sweep's reruns under -plan, or is nil
+var planned *planRecorder
+
+// Add records that run in the repository would be re-run
+func (p *planRecorder) Add(fullName string, run WorkflowRun) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.entries = append(p.entries, planEntry{
+		Repo:       fullName,
+		RunID:      run.ID,
+		Workflow:   run.Name,
+		RunAttempt: run.RunAttempt,
+		UpdatedAt:  run.UpdatedAt,
+	})
+}
+
+// planChecksum returns the checksum of a plan's actions
+func planChecksum(actions []planEntry) (string, error) {
+	data, err := json.Marshal(actions)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Write writes the recorded reruns to path for review before -apply
+func (p *planRecorder) Write(path string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	plan := planFile{CreatedAt: time.Now().UTC(), Actions: p.entries}
+	if plan.Actions == nil {
+		plan.Actions = []planEntry{}
+	}
+	checksum, err := planChecksum(plan.Actions)
+	if err != nil {
+		return err
+	}
+	plan.Checksum = checksum
+
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, append(data, '\n')); err != nil {
+		return err
+	}
+	summaryf("Wrote plan of %d reruns to %s; review it and run with -apply %s\n", len(plan.Actions), path, path)
+	return nil
+}
+
+// readPlan reads the plan at path and verifies its checksum
+func readPlan(path string) (planFile, error) {
+	var plan planFile
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return plan, err
+	}
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return plan, fmt.Errorf("invalid plan file %s: %v", path, err)
+	}
+	checksum, err := planChecksum(plan.Actions)
+	if err != nil {
+		return plan, err
+	}
+	if checksum != plan.Checksum {
+		return plan, fmt.Errorf("plan file %s does not match its checksum; it was modified after it was written", path)
+	}
+	return plan, nil
+}
+
+// processPlan re-runs exactly the runs in the -apply plan, skipping any run
+// that was re-run or otherwise updated since the plan was written
+func processPlan(ctx context.Context) ([]Result, error) {
+	plan, err := readPlan(config.Apply)
+	if err != nil {
+		return nil, err
+	}
+	if age := time.Since(plan.CreatedAt); config.PlanMaxAge > 0 && age > config.PlanMaxAge {
+		logger.Warn("applying a stale plan; runs may have changed since it was reviewed", "created", plan.CreatedAt.Format(time.RFC3339), "age", age.Round(time.Second))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results, firstErr := runPool(ctx, cancel, plan.Actions, applyPlanEntry)
+	summaryf("Applied %d of %d planned reruns from %s\n", countAction(results, ActionRerun), len(plan.Actions), config.Apply)
+
+	if config.FailFast {
+		return results, firstErr
+	}
+	return results, nil
+}
+
+// applyPlanEntry re-runs a planned run after checking it is still the run
+// the plan saw
+func applyPlanEntry(ctx context.Context, entry planEntry) Result {
+	repo := repositoryFromPath("", entry.Repo)
+	run, err := getWorkflowRun(withoutCache(ctx), entry.Repo, entry.RunID)
+	if isStatus(err, http.StatusNotFound) {
+		err = fmt.Errorf("run %d no longer exists in %s", entry.RunID, entry.Repo)
+	}
+	if err != nil {
+		errorf("Not re-running %s run %d: %v\n", entry.Repo, entry.RunID, err)
+		return newResult(repo, "").withRun(WorkflowRun{ID: entry.RunID, Name: entry.Workflow}).fail(err)
+	}
+	if run.RunAttempt != entry.RunAttempt || !run.UpdatedAt.Equal(entry.UpdatedAt) {
+		progressf("Skipping %s run %d: changed since the plan was written\n", entry.Repo, entry.RunID)
+		return newResult(repo, "").withRun(run).skip("run changed since the plan was written")
+	}
+	return rerunRepoRun(ctx, repoRun{Repo: repo, Run: run})
+}
+
+// countAction returns how many results have action
+func countAction(results []Result, action string) int {
+	n := 0
+	for _, result := range results {
+		if result.Action == action {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+)
+
+// redactedSecret replaces every secret value in the -print-config dump and,
+// through redact, in progress, log and error output
+const redactedSecret = "***"
+
+// configDump is the shape printed by -print-config
+type configDump struct {
+	Tokens     []string      `json:"tokens"`
+	Enterprise string        `json:"enterprise,omitempty"`
+	ConfigFile string        `json:"config_file,omitempty"`
+	Profiles   []profileDump `json:"profiles"`
+}
+
+// profileDump is one organization's effective configuration
+type profileDump struct {
+	Org    string `json:"org"`
+	Config Config `json:"config"`
+}
+
+// printConfig writes the fully resolved configuration to stdout as JSON with
+// every token replaced by "***" and proxy credentials removed
+func printConfig(tokenList []string) error {
+	profiles, err := loadProfiles(config)
+	if err != nil {
+		return err
+	}
+
+	dump := configDump{
+		Enterprise: config.Enterprise,
+		ConfigFile: config.ConfigFile,
+	}
+	for range tokenList {
+		dump.Tokens = append(dump.Tokens, redactedSecret)
+	}
+	for _, profile := range profiles {
+		cfg := profile.Config
+		cfg.Proxy = redactURLCredentials(cfg.Proxy)
+		dump.Profiles = append(dump.Profiles, profileDump{Org: profile.Org, Config: cfg})
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(dump)
+}
+
+// redactURLCredentials hides the password of a URL with user info
+func redactURLCredentials(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return u.Redacted()
+}
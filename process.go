@@ -0,0 +1,665 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// repoRun pairs a repository with the workflow run selected for it
+type repoRun struct {
+	Repo Repository
+	Run  WorkflowRun
+}
+
+// runSweep processes every organization profile once (or just the runs listed
+// in -runs-file) and writes the combined results. It returns the error
+// opening the output, the -fail-fast error that aborted the sweep, if any, or
+// with -strict-json the error that stopped the output.
+func runSweep(ctx context.Context, profiles []orgProfile) (sweepErr error) {
+	base := config
+	defer func() { config = base }()
+
+	sweepIteration++
+	triggeredRuns = newRunSet()
+	defer func() { triggeredRuns = nil }()
+	sink, err := startResultSink()
+	if err != nil {
+		return fmt.Errorf("opening output: %w", err)
+	}
+	activeSink = sink
+	defer func() {
+		activeSink = nil
+		if err := sink.Close(); err != nil {
+			errorf("Error writing results: %v\n", err)
+			if config.StrictJSON && sweepErr == nil {
+				sweepErr = err
+			}
+		}
+	}()
+
+	counters := startSweepCounters()
+	var orgs []string
+	for _, profile := range profiles {
+		orgs = append(orgs, profile.Org)
+	}
+
+	var all []Result
+	var partial bool
+	switch {
+	case config.RunsFile != "":
+		all, sweepErr = processRunsFile(ctx, profiles[0].Org)
+		profiles = nil
+	case config.RunID > 0:
+		all, sweepErr = processRunID(ctx, profiles[0].Org)
+		profiles = nil
+	case config.RerunSHA != "":
+		all, sweepErr = processRerunSHA(ctx, profiles[0].Org)
+		profiles = nil
+	case config.Apply != "":
+		all, sweepErr = processPlan(ctx)
+		profiles = nil
+	}
+	if config.ParallelOrgs > 1 && len(profiles) > 1 {
+		all, partial, sweepErr = processOrganizationsParallel(ctx, profiles)
+		profiles = nil
+	}
+	for _, profile := range profiles {
+		config = profile.Config
+		results, incomplete, err := processOrganization(ctx, profile.Org)
+		all = append(all, results...)
+		partial = partial || incomplete
+		if err != nil {
+			sweepErr = err
+			break
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	config = base
+
+	responseCache.Clear()
+	clearMap(&ignoreFileCache)
+	clearMap(&codeownersCache)
+	if err := checkpointState.Save(); err != nil {
+		errorf("Error writing checkpoint: %v\n", err)
+	}
+	if err := repoCursors.Save(); err != nil {
+		errorf("Error writing repository cursor: %v\n", err)
+	}
+	logStats()
+	if config.DryRun {
+		printCostEstimate(counters)
+	}
+	if config.ReportSlowest > 0 {
+		reportSlowest(all, config.ReportSlowest)
+	}
+	summary := buildSummary(orgs, counters, all, partial)
+	printConclusions(summary.Conclusions)
+	if config.GroupByOrg {
+		printOrgTable(summary)
+	}
+	if config.GroupBy == "conclusion" {
+		printConclusionGroups(all)
+	}
+	if config.Monitor {
+		reportHealth(all)
+	}
+	if config.SummaryJSON != "" {
+		if err := writeSummary(config.SummaryJSON, summary); err != nil {
+			errorf("Error writing summary: %v\n", err)
+		}
+	}
+	if planned != nil {
+		if err := planned.Write(config.Plan); err != nil {
+			errorf("Error writing plan: %v\n", err)
+			if sweepErr == nil {
+				sweepErr = err
+			}
+		}
+	}
+	if config.PostHook != "" {
+		if err := runPostHook(config.PostHook, summary); err != nil {
+			errorf("Error running post-hook: %v\n", err)
+			if config.PostHookRequired && sweepErr == nil {
+				sweepErr = err
+			}
+		}
+	}
+	if config.FailuresFile != "" {
+		if err := writeFailuresFile(config.FailuresFile, all); err != nil {
+			errorf("Error writing failures file: %v\n", err)
+		}
+	}
+	if err := writeStepSummary(all); err != nil {
+		errorf("Error writing job summary: %v\n", err)
+	}
+
+	failed := sweepErr != nil
+	for _, result := range all {
+		failed = failed || result.err != nil
+	}
+	releaseOutput(failed)
+	return sweepErr
+}
+
+// processOrganizationsParallel processes up to -parallel-orgs organizations
+// at once, each with its own worker pool while sharing the rate limiters, and
+// returns their results in profile order. The organizations share the
+// global config, so none may have a -config profile. With -fail-fast the
+// first error cancels the other organizations and is returned.
+func processOrganizationsParallel(ctx context.Context, profiles []orgProfile) (all []Result, partial bool, err error) {
+	for _, profile := range profiles {
+		if profile.Overridden {
+			return nil, false, fmt.Errorf("-parallel-orgs needs the same settings for every organization, but %s has a -config profile", profile.Org)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		results  = make([][]Result, len(profiles))
+		firstErr error
+	)
+	slots := make(chan struct{}, config.ParallelOrgs)
+	for i, profile := range profiles {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			orgResults, incomplete, orgErr := processOrganization(ctx, profile.Org)
+			mu.Lock()
+			defer mu.Unlock()
+			results[i] = orgResults
+			partial = partial || incomplete
+			if orgErr != nil && firstErr == nil {
+				firstErr = orgErr
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, orgResults := range results {
+		all = append(all, orgResults...)
+	}
+	return all, partial, firstErr
+}
+
+// processOrganization lists the organization's repositories and applies the
+// selected per-repository action using a pool of config.Concurrency workers.
+// Repositories are processed while the listing is still paging, unless
+// -buffer-listing or an ordered rerun (-oldest-first, -limit) needs the full
+// list first. With -fail-fast the first repository error cancels the
+// remaining work and is returned; otherwise errors are counted and reported
+// in the summary. A listing failure is recorded as an error result, and with
+// -fail-fast returned, unless -best-effort-listing accepts the repositories
+// listed before it, in which case partial is set.
+func processOrganization(ctx context.Context, org string) (results []Result, partial bool, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if config.CheckOrgPermissions {
+		checkOrgPermissions(ctx, org)
+	}
+
+	action := selectAction()
+
+	var (
+		repos    []Repository
+		firstErr error
+	)
+	if action != nil && !config.BufferListing {
+		bar.Reset(0, config.Concurrency)
+		stream := streamRepositories(ctx, org)
+		results, firstErr = runFeed(ctx, cancel, stream.Next, action)
+
+		var listErr error
+		repos, listErr = stream.Stop()
+		if listErr != nil {
+			if len(repos) > 0 && config.BestEffortListing {
+				errorf("Listing repositories in %s stopped after %d repositories: %v; processed those listed\n", org, len(repos), listErr)
+				partial = true
+			} else {
+				errorf("Error fetching repositories: %v\n", listErr)
+				result := Result{Org: org, Action: ActionError, Error: listErr.Error(), err: listErr}
+				emitResult(result)
+				if len(results) == 0 {
+					bar.Finish()
+					return []Result{result}, false, listingFailFastError(org, listErr)
+				}
+				results = append(results, result)
+				if firstErr == nil {
+					firstErr = listingFailFastError(org, listErr)
+				}
+			}
+		}
+	} else {
+		repos, err = getRepositories(ctx, org)
+		if err != nil {
+			if !config.BestEffortListing || len(repos) == 0 {
+				errorf("Error fetching repositories: %v\n", err)
+				result := Result{Org: org, Action: ActionError, Error: err.Error(), err: err}
+				emitResult(result)
+				return []Result{result}, false, listingFailFastError(org, err)
+			}
+			errorf("Listing repositories in %s stopped after %d repositories: %v; continuing with those collected\n", org, len(repos), err)
+			partial = true
+		}
+		repos = capRepositories(filterRepositories(repos))
+		bar.Reset(len(repos), config.Concurrency)
+
+		if action == nil {
+			results, firstErr = processRerunsOrdered(ctx, cancel, repos)
+		} else {
+			results, firstErr = runPool(ctx, cancel, repos, action)
+		}
+	}
+	bar.Finish()
+
+	if quotaReserveReached() {
+		results = append(results, unprocessedResults(repos, results)...)
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.err != nil {
+			failed++
+		}
+	}
+	summaryf("Processed %d of %d repositories in %s, %d errors\n", len(results), len(repos), org, failed)
+
+	if config.FailFast {
+		return results, partial, firstErr
+	}
+	return results, partial, nil
+}
+
+// listingFailFastError returns the error that aborts the sweep when listing
+// org's repositories failed under -fail-fast, or nil without it
+func listingFailFastError(org string, err error) error {
+	if !config.FailFast {
+		return nil
+	}
+	return fmt.Errorf("listing repositories in %s: %w", org, err)
+}
+
+// runPool applies fn to every item using config.Concurrency workers and
+// collects the results (see runFeed)
+func runPool[T any](ctx context.Context, cancel context.CancelFunc, items []T, fn func(context.Context, T) Result) ([]Result, error) {
+	i := 0
+	return runFeed(ctx, cancel, func() (T, bool) {
+		if i >= len(items) {
+			var zero T
+			return zero, false
+		}
+		i++
+		return items[i-1], true
+	}, fn)
+}
+
+// runFeed applies fn to each item returned by next, until next reports no
+// more, using config.Concurrency workers, and collects the results. It
+// returns the first error result; with -fail-fast that error also cancels ctx
+// so no further items are started. A single worker waits -delay between
+// items.
+func runFeed[T any](ctx context.Context, cancel context.CancelFunc, next func() (T, bool), fn func(context.Context, T) Result) ([]Result, error) {
+	workers := config.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		results  []Result
+		firstErr error
+	)
+
+	jobs := make(chan T)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			paced := false
+			for item := range jobs {
+				if workers == 1 && config.Delay > 0 {
+					if paced && sleepContext(ctx, config.Delay) != nil {
+						continue
+					}
+					paced = true
+				}
+				emitEvent(ProgressEvent{Kind: EventRepoStarted, Repo: itemName(item)})
+				start := time.Now()
+				result := runWithTimeout(ctx, item, fn)
+				elapsed := time.Since(start)
+				result.DurationMS = elapsed.Milliseconds()
+
+				if result.Action != "" {
+					emitResult(result)
+					bar.Complete(elapsed)
+					emitEvent(ProgressEvent{Kind: EventRepoDone, Repo: result.Org + "/" + result.Repo, Action: result.Action, Error: result.Error})
+				}
+
+				mu.Lock()
+				results = append(results, result)
+				if result.err != nil && ctx.Err() == nil {
+					if firstErr == nil {
+						firstErr = fmt.Errorf("%s: %w", result.Repo, result.err)
+					}
+					if config.FailFast {
+						cancel()
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for {
+		if quotaReserveReached() {
+			break
+		}
+		item, ok := next()
+		if !ok {
+			break
+		}
+		select {
+		case jobs <- item:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, firstErr
+}
+
+// runWithTimeout applies fn to item under the -repo-timeout deadline. A
+// repository that exceeds it is recorded as timed out so the sweep can move on.
+func runWithTimeout[T any](ctx context.Context, item T, fn func(context.Context, T) Result) Result {
+	if config.RepoTimeout <= 0 {
+		return fn(ctx, item)
+	}
+
+	itemCtx, cancel := context.WithTimeout(ctx, config.RepoTimeout)
+	defer cancel()
+
+	result := fn(itemCtx, item)
+	if result.err != nil && itemCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		errorf("Timed out processing %s after %s\n", result.Repo, config.RepoTimeout)
+		result.Action = ActionTimedOut
+		result.Reason = fmt.Sprintf("exceeded -repo-timeout of %s", config.RepoTimeout)
+	}
+	return result
+}
+
+// unprocessedResults reports the repositories that received no result because
+// the -min-remaining quota reserve stopped the sweep early
+func unprocessedResults(repos []Repository, results []Result) []Result {
+	done := make(map[string]bool, len(results))
+	for _, result := range results {
+		done[result.Org+"/"+result.Repo] = true
+	}
+
+	var skipped []Result
+	for _, repo := range repos {
+		if !done[repo.FullName] {
+			skipped = append(skipped, newResult(repo, "").skip("rate limit reserve reached"))
+		}
+	}
+	if len(skipped) > 0 {
+		summaryf("Stopped early: rate limit remaining at or below %d, %d repositories not processed\n", config.MinRemaining, len(skipped))
+		for _, result := range skipped {
+			summaryf("  %s\n", result.Repo)
+			emitResult(result)
+		}
+	}
+	return skipped
+}
+
+// selectAction returns the per-repository action chosen by the mode flags.
+// Re-running the latest workflow run is the default. It returns nil when
+// reruns must be ordered or limited, which needs every run fetched before
+// any is re-triggered (see processRerunsOrdered).
+func selectAction() func(context.Context, Repository) Result {
+	switch {
+	case config.DeleteArtifactsOlderThan > 0:
+		return cleanupArtifacts
+	case config.EnableWorkflow != "" || config.DisableWorkflow != "":
+		return toggleWorkflow
+	case config.Approve:
+		return approveWaitingRuns
+	case config.ApproveDeployments:
+		return approveDeployments
+	case config.CheckRunners:
+		return checkRunners
+	case config.Dispatch != "":
+		return dispatchRepository
+	case config.DispatchAllWorkflows:
+		return dispatchAllWorkflows
+	case config.ListWorkflows:
+		return listRepositoryWorkflows
+	case config.Monitor:
+		return monitorRepository
+	case config.OldestFirst || config.Limit > 0:
+		return nil
+	default:
+		return processRepository
+	}
+}
+
+// processRepository re-triggers the latest workflow run of a single repository
+func processRepository(ctx context.Context, repo Repository) Result {
+	progressf("Processing repository: %s\n", repo.Name)
+
+	latestRun, result, ok := selectRun(ctx, repo)
+	if !ok {
+		return result
+	}
+
+	return rerunRepoRun(ctx, repoRun{Repo: repo, Run: latestRun})
+}
+
+// skipMissingWorkflow is the result for a repository missingWorkflowReason
+// excluded, or whose workflows could not be listed. Exclusions are only
+// logged at debug level, since most repositories of a large organization
+// may lack the workflow.
+func skipMissingWorkflow(repo Repository, reason string, err error) Result {
+	if err != nil {
+		errorf("Error listing workflows for %s: %v\n", repo.Name, err)
+		return newResult(repo, "").fail(err)
+	}
+	logger.Debug("skipping repository", "repo", repo.Name, "reason", reason)
+	return newResult(repo, "").skip(reason)
+}
+
+// selectRun fetches the repository's latest workflow run and applies the run
+// filters. When ok is false the repository is finished and result says why.
+func selectRun(ctx context.Context, repo Repository) (run WorkflowRun, result Result, ok bool) {
+	if reason, err := missingWorkflowReason(ctx, repo); err != nil || reason != "" {
+		return WorkflowRun{}, skipMissingWorkflow(repo, reason, err), false
+	}
+
+	latestRun, err := getLatestWorkflowRun(ctx, repo)
+	if err != nil && isStatus(err, http.StatusNotFound) && (config.WorkflowID > 0 || config.WorkflowFile != "") {
+		progressf("Skipping %s: selected workflow not present\n", repo.Name)
+		return WorkflowRun{}, newResult(repo, "").skip("selected workflow not present in repository"), false
+	}
+	if err != nil {
+		errorf("Error fetching latest workflow run for %s: %v\n", repo.Name, err)
+		return WorkflowRun{}, newResult(repo, "").fail(err), false
+	}
+
+	reason := runSkipReason(latestRun)
+	if reason == "" && checkpointState.Unchanged(repo.FullName, latestRun) {
+		reason = "run unchanged since this tool last re-triggered it"
+	}
+	if reason == "" {
+		if ago, cooling := checkpointState.InCooldown(repo.FullName, latestRun); cooling {
+			reason = fmt.Sprintf("in cooldown, re-run %s ago (-cooldown %s)", ago.Round(time.Second), config.Cooldown)
+		}
+	}
+	if reason == "" && config.HonorIgnoreFile {
+		ignored, err := hasIgnoreFile(ctx, repo)
+		if err != nil {
+			errorf("Error checking %s for %s: %v\n", ignoreFileName, repo.Name, err)
+			return latestRun, newResult(repo, "").withRun(latestRun).fail(err), false
+		}
+		if ignored {
+			reason = "opted out via " + ignoreFileName
+		}
+	}
+	if reason == "" && config.SkipOfflineRunners {
+		reason = runnersOfflineReason(ctx, repo)
+	}
+	if reason == "" && config.RequireFailedJob != "" {
+		reason, err = requiredJobReason(ctx, repo.FullName, latestRun)
+		if err != nil && config.JobFallback && jobsUnavailable(err) {
+			logger.Warn("jobs unavailable, re-running the whole run", "repo", repo.FullName, "run_id", latestRun.ID, "error", err)
+			reason, err = "", nil
+		}
+		if err != nil {
+			errorf("Error listing jobs of run %d in %s: %v\n", latestRun.ID, repo.Name, err)
+			return latestRun, newResult(repo, "").withRun(latestRun).fail(err), false
+		}
+	}
+	if reason != "" {
+		progressf("Skipping %s: %s\n", repo.Name, reason)
+		return latestRun, newResult(repo, "").withRun(latestRun).skip(reason), false
+	}
+
+	return latestRun, Result{}, true
+}
+
+// processRerunsOrdered fetches the latest run of every repository first,
+// sorts the runs oldest first when -oldest-first is set, and only then
+// re-triggers them. -limit is applied after sorting, so combined with
+// -oldest-first it re-runs the N most neglected repositories; repositories
+// beyond the limit are reported as skipped.
+func processRerunsOrdered(ctx context.Context, cancel context.CancelFunc, repos []Repository) ([]Result, error) {
+	var (
+		mu    sync.Mutex
+		found []repoRun
+	)
+
+	lookups, firstErr := runPool(ctx, cancel, repos, func(ctx context.Context, repo Repository) Result {
+		progressf("Processing repository: %s\n", repo.Name)
+
+		latestRun, result, ok := selectRun(ctx, repo)
+		if !ok {
+			return result
+		}
+
+		mu.Lock()
+		found = append(found, repoRun{Repo: repo, Run: latestRun})
+		mu.Unlock()
+		return newResult(repo, "")
+	})
+
+	var results []Result
+	for _, lookup := range lookups {
+		if lookup.Action != "" {
+			results = append(results, lookup)
+		}
+	}
+	if config.FailFast && firstErr != nil {
+		return results, firstErr
+	}
+
+	if config.OldestFirst {
+		sort.SliceStable(found, func(i, j int) bool {
+			return found[i].Run.CreatedAt.Before(found[j].Run.CreatedAt)
+		})
+	}
+	if config.Limit > 0 && len(found) > config.Limit {
+		for _, skipped := range found[config.Limit:] {
+			result := newResult(skipped.Repo, "").withRun(skipped.Run).skip("beyond -limit")
+			emitResult(result)
+			results = append(results, result)
+		}
+		found = found[:config.Limit]
+	}
+
+	reruns, err := runPool(ctx, cancel, found, rerunRepoRun)
+	if firstErr == nil {
+		firstErr = err
+	}
+	return append(results, reruns...), firstErr
+}
+
+// runSet is a set of workflow run IDs safe for concurrent use
+type runSet struct {
+	mu  sync.Mutex
+	ids map[int]bool
+}
+
+// triggeredRuns holds the runs re-triggered in the current sweep, so a run
+// that overlapping selections pick (repeated -runs-file lines or plan
+// entries, or the same run reached through several repositories) is
+// re-triggered once. It is nil outside a sweep, so -serve may re-run a run
+// again once its cooldown passes.
+var triggeredRuns *runSet
+
+// newRunSet returns an empty runSet
+func newRunSet() *runSet {
+	return &runSet{ids: make(map[int]bool)}
+}
+
+// claim adds id to the set and reports whether it was not there yet. A nil
+// set claims every ID.
+func (s *runSet) claim(id int) bool {
+	if s == nil {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ids[id] {
+		return false
+	}
+	s.ids[id] = true
+	return true
+}
+
+// rerunRepoRun re-triggers the run selected for a repository, unless the
+// sweep already re-triggered it
+func rerunRepoRun(ctx context.Context, selected repoRun) Result {
+	fullName, run := selected.Repo.FullName, selected.Run
+	result := newResult(selected.Repo, ActionRerun).withRun(run)
+
+	if !triggeredRuns.claim(run.ID) {
+		logger.Debug("skipping duplicate run", "repo", fullName, "run_id", run.ID)
+		return result.skip("run already re-triggered in this sweep")
+	}
+
+	progressf("Re-running workflow: %s (Run ID: %d)\n", run.Name, run.ID)
+	if err := rerunWorkflow(ctx, fullName, run.ID); err != nil {
+		errorf("Failed to re-run workflow for %s: %v\n", fullName, err)
+		return result.fail(err)
+	}
+	progressf("Successfully re-ran workflow for %s\n", fullName)
+	if config.Reason != "" {
+		logger.Info("re-triggered run", "repo", fullName, "run_id", run.ID, "reason", config.Reason)
+	}
+	checkpointState.Record(fullName, run)
+	planned.Add(fullName, run)
+	return result
+}
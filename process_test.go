@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOverlappingSelectionsRerunOnce(t *testing.T) {
+	var counter requestCounter
+	useTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		counter.add(r)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	config.Concurrency = 4
+	triggeredRuns = newRunSet()
+
+	r1 := repositoryFromPath("acme", "r1")
+	r2 := repositoryFromPath("acme", "r2")
+	selections := []repoRun{
+		{Repo: r1, Run: WorkflowRun{ID: 11}},
+		{Repo: r2, Run: WorkflowRun{ID: 22}},
+		{Repo: r1, Run: WorkflowRun{ID: 11}},
+		{Repo: r1, Run: WorkflowRun{ID: 11}},
+		{Repo: r2, Run: WorkflowRun{ID: 22}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	results, err := runPool(ctx, cancel, selections, rerunRepoRun)
+	if err != nil {
+		t.Fatalf("runPool: %v", err)
+	}
+
+	for _, path := range []string{"/repos/acme/r1/actions/runs/11/rerun", "/repos/acme/r2/actions/runs/22/rerun"} {
+		if got := counter.get("POST " + path); got != 1 {
+			t.Errorf("POST %s sent %d times, want 1", path, got)
+		}
+	}
+	if got := countAction(results, ActionRerun); got != 2 {
+		t.Errorf("%d rerun results, want 2", got)
+	}
+	if got := countAction(results, ActionSkipped); got != 3 {
+		t.Errorf("%d skipped results, want 3", got)
+	}
+}
+
+func TestRunsRerunAgainOutsideSweep(t *testing.T) {
+	var counter requestCounter
+	useTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		counter.add(r)
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	target := repoRun{Repo: repositoryFromPath("acme", "r1"), Run: WorkflowRun{ID: 11}}
+	for i := 0; i < 2; i++ {
+		if result := rerunRepoRun(context.Background(), target); result.Action != ActionRerun {
+			t.Fatalf("rerun %d: action %q, want %q", i, result.Action, ActionRerun)
+		}
+	}
+	if got := counter.get("POST /repos/acme/r1/actions/runs/11/rerun"); got != 2 {
+		t.Errorf("POST sent %d times, want 2", got)
+	}
+}
+
+func TestSweepFailsWhenOutputCannotOpen(t *testing.T) {
+	var counter requestCounter
+	useTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		counter.add(r)
+		w.Write([]byte("[]"))
+	}))
+	notDir := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(notDir, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config.Output = "json"
+	config.OutputFile = filepath.Join(notDir, "results.json")
+
+	err := runSweep(context.Background(), []orgProfile{{Org: "acme", Config: config}})
+	if err == nil || !strings.HasPrefix(err.Error(), "opening output: ") {
+		t.Fatalf("runSweep error = %v, want an output error", err)
+	}
+	if n := counter.get("GET /orgs/acme/repos"); n != 0 {
+		t.Errorf("listed repositories %d times without an output", n)
+	}
+}
+
+func TestListingFailureUnderFailFast(t *testing.T) {
+	for _, buffered := range []bool{false, true} {
+		for _, failFast := range []bool{false, true} {
+			t.Run(fmt.Sprintf("buffered=%v/fail-fast=%v", buffered, failFast), func(t *testing.T) {
+				useTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusForbidden)
+					w.Write([]byte(`{"message":"listing denied"}`))
+				}))
+				config.Concurrency = 1
+				config.BufferListing = buffered
+				config.FailFast = failFast
+
+				results, _, err := processOrganization(context.Background(), "acme")
+				if len(results) != 1 || results[0].Action != ActionError {
+					t.Errorf("results = %+v, want one error result", results)
+				}
+				if failFast && !isStatus(err, http.StatusForbidden) {
+					t.Errorf("error = %v, want the listing error under -fail-fast", err)
+				}
+				if !failFast && err != nil {
+					t.Errorf("error = %v, want nil without -fail-fast", err)
+				}
+			})
+		}
+	}
+}
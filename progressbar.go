@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// progressBarWidth is the number of cells in the drawn bar
+const progressBarWidth = 30
+
+// progressBarWindow is how many recent per-repository durations feed the ETA
+const progressBarWindow = 20
+
+// progressBar draws completed/total and an ETA on stderr, redrawing in place
+type progressBar struct {
+	mu        sync.Mutex
+	total     int
+	completed int
+	recent    []time.Duration
+	workers   int
+}
+
+// bar is the active progress bar, or nil when -bar is off or unsupported
+var bar *progressBar
+
+// newProgressBar returns a bar when -bar is set, stderr is a terminal and the
+// output is human-readable and not quieted; otherwise it returns nil
+func newProgressBar() *progressBar {
+	if !config.Bar || config.Output != "text" || config.Quiet || config.QuietUnlessError || !isTerminal(os.Stderr) {
+		return nil
+	}
+	return &progressBar{}
+}
+
+// isTerminal reports whether f is attached to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Reset starts tracking a new batch of total repositories processed by workers
+func (b *progressBar) Reset(total, workers int) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.total, b.completed, b.recent, b.workers = total, 0, nil, max(workers, 1)
+	b.draw()
+}
+
+// Complete records one repository finishing after d and redraws the bar
+func (b *progressBar) Complete(d time.Duration) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.total == 0 {
+		return
+	}
+	b.completed++
+	b.recent = append(b.recent, d)
+	if len(b.recent) > progressBarWindow {
+		b.recent = b.recent[1:]
+	}
+	b.draw()
+}
+
+// Grow adds n repositories to the total, for listings still in progress
+func (b *progressBar) Grow(n int) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.total += n
+	b.draw()
+}
+
+// Finish ends the bar's line once a batch is done
+func (b *progressBar) Finish() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.total > 0 {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+// draw renders the bar; the caller holds b.mu
+func (b *progressBar) draw() {
+	if b.total == 0 {
+		return
+	}
+
+	filled := progressBarWidth * b.completed / b.total
+	line := fmt.Sprintf("[%s%s] %d/%d", strings.Repeat("#", filled), strings.Repeat(".", progressBarWidth-filled), b.completed, b.total)
+	if eta := b.eta(); eta > 0 {
+		line += fmt.Sprintf(" ETA %s", eta.Round(time.Second))
+	}
+	fmt.Fprintf(os.Stderr, "\r\033[K%s", line)
+}
+
+// eta estimates the remaining time from the rolling average duration, divided
+// across the workers processing repositories in parallel
+func (b *progressBar) eta() time.Duration {
+	if len(b.recent) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, d := range b.recent {
+		sum += d
+	}
+	avg := sum / time.Duration(len(b.recent))
+	return avg * time.Duration(b.total-b.completed) / time.Duration(b.workers)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// quotaSampleInterval is the least time between two quota samples
+const quotaSampleInterval = 10 * time.Second
+
+// QuotaSample is the core API quota a response reported at a moment
+type QuotaSample struct {
+	At        time.Time `json:"at"`
+	Remaining int       `json:"remaining"`
+	Limit     int       `json:"limit"`
+	Reset     time.Time `json:"reset"`
+}
+
+// quotaTracker samples the rate-limit headers of responses into a time
+// series, so a sweep shows how fast it drains the quota
+type quotaTracker struct {
+	mu      sync.Mutex
+	samples []QuotaSample
+}
+
+// quota holds the samples taken during this invocation
+var quota quotaTracker
+
+// Observe samples the core quota reported by resp, at most once per
+// quotaSampleInterval. Samples are only taken with -track-quota or debug
+// logging, and each is logged as it is taken.
+func (q *quotaTracker) Observe(resp *http.Response) {
+	if !config.TrackQuota && !logger.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+	if resource := resp.Header.Get("X-RateLimit-Resource"); resource != "" && resource != "core" {
+		return
+	}
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	limit, _ := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	reset, _ := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+
+	now := time.Now()
+	q.mu.Lock()
+	if n := len(q.samples); n > 0 && now.Sub(q.samples[n-1].At) < quotaSampleInterval {
+		q.mu.Unlock()
+		return
+	}
+	sample := QuotaSample{At: now, Remaining: remaining, Limit: limit, Reset: time.Unix(reset, 0)}
+	q.samples = append(q.samples, sample)
+	q.mu.Unlock()
+
+	level := slog.LevelDebug
+	if config.TrackQuota {
+		level = slog.LevelInfo
+	}
+	logger.Log(context.Background(), level, "rate limit quota", "remaining", remaining, "limit", limit, "reset", sample.Reset.Format(time.RFC3339))
+}
+
+// Len returns the number of samples taken so far
+func (q *quotaTracker) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.samples)
+}
+
+// Since returns the samples taken after the first n
+func (q *quotaTracker) Since(n int) []QuotaSample {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if n >= len(q.samples) {
+		return nil
+	}
+	return append([]QuotaSample(nil), q.samples[n:]...)
+}
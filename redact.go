@@ -0,0 +1,52 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"sync"
+)
+
+// secrets are the values redact masks: the configured tokens and the webhook
+// secret. They are set once the tokens are loaded.
+var (
+	secretsMu sync.RWMutex
+	secrets   []string
+)
+
+// setSecrets records the values redact masks from now on
+func setSecrets(values []string) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	secrets = nil
+	for _, value := range values {
+		if value != "" {
+			secrets = append(secrets, value)
+		}
+	}
+}
+
+// redact replaces every secret in s with ***. It guards progress lines, log
+// lines and result errors, so a token that ends up in a URL or an error
+// message never reaches CI logs or output files.
+func redact(s string) string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, redactedSecret)
+	}
+	return s
+}
+
+// redactingWriter redacts each write before passing it on. Writers such as
+// slog's handlers write one whole line at a time, so a secret is never split
+// across writes.
+type redactingWriter struct {
+	w io.Writer
+}
+
+func (r redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// useSecrets makes redact mask values for the rest of the test
+func useSecrets(t *testing.T, values ...string) {
+	t.Helper()
+	secretsMu.RLock()
+	saved := secrets
+	secretsMu.RUnlock()
+	t.Cleanup(func() { setSecrets(saved) })
+	setSecrets(values)
+}
+
+func TestTokenRedactedInResultError(t *testing.T) {
+	useTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A misbehaving proxy echoing the credentials it rejected
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintf(w, `{"message":"bad credentials: %s"}`, r.Header.Get("Authorization"))
+	}))
+	useSecrets(t, testToken)
+
+	err := rerunWorkflow(context.Background(), "acme/r1", 11)
+	if err == nil {
+		t.Fatal("rerunWorkflow succeeded, want HTTP 401")
+	}
+	result := newResult(repositoryFromPath("acme", "r1"), ActionRerun).fail(err)
+	if strings.Contains(result.Error, testToken) {
+		t.Errorf("result error %q contains the token", result.Error)
+	}
+	if want := "bad credentials: Bearer " + redactedSecret; !strings.Contains(result.Error, want) {
+		t.Errorf("result error %q, want it to contain %q", result.Error, want)
+	}
+}
+
+func TestRedactingWriter(t *testing.T) {
+	useSecrets(t, testToken, "", "webhook-secret")
+
+	var buf bytes.Buffer
+	line := "token " + testToken + " and secret webhook-secret\n"
+	n, err := redactingWriter{&buf}.Write([]byte(line))
+	if err != nil || n != len(line) {
+		t.Fatalf("Write = %d, %v; want %d, nil", n, err, len(line))
+	}
+	if want := "token " + redactedSecret + " and secret " + redactedSecret + "\n"; buf.String() != want {
+		t.Errorf("wrote %q, want %q", buf.String(), want)
+	}
+}
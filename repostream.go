@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+// errRepoCapReached ends a streamed listing once -max-repos-per-org
+// repositories have been handed out
+var errRepoCapReached = errors.New("-max-repos-per-org reached")
+
+// repoStream lists an organization's repositories in the background and
+// hands each one that passes the repository filters to the worker pool as
+// soon as its page arrives, so processing overlaps listing. Listing stops
+// early once -max-repos-per-org repositories have been handed out.
+type repoStream struct {
+	repos  chan Repository
+	cancel context.CancelFunc
+	done   chan struct{}
+	listed []Repository
+	err    error
+}
+
+// streamRepositories starts listing org's repositories
+func streamRepositories(ctx context.Context, org string) *repoStream {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &repoStream{repos: make(chan Repository), cancel: cancel, done: make(chan struct{})}
+
+	go func() {
+		defer close(s.done)
+		defer close(s.repos)
+		err := eachRepositoryPage(ctx, org, func(batch []Repository) error {
+			for _, repo := range filterRepositories(batch) {
+				select {
+				case s.repos <- repo:
+					s.listed = append(s.listed, repo)
+					bar.Grow(1)
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+				if config.MaxReposPerOrg > 0 && len(s.listed) >= config.MaxReposPerOrg {
+					return errRepoCapReached
+				}
+			}
+			return nil
+		})
+		if ctx.Err() == nil && !errors.Is(err, errRepoCapReached) {
+			s.err = err
+		}
+	}()
+	return s
+}
+
+// Next returns the next listed repository; ok is false once listing has ended
+func (s *repoStream) Next() (repo Repository, ok bool) {
+	repo, ok = <-s.repos
+	return repo, ok
+}
+
+// Stop ends the listing if it is still running and returns the repositories
+// handed out and the listing error. Listing cut short by Stop or by the
+// context being cancelled is not an error.
+func (s *repoStream) Stop() ([]Repository, error) {
+	s.cancel()
+	<-s.done
+	return s.listed, s.err
+}
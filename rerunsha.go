@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// processRerunSHA re-runs the newest run of -repo whose head commit is
+// -rerun-sha. Runs are scanned up to -max-run-pages pages.
+func processRerunSHA(ctx context.Context, defaultOrg string) ([]Result, error) {
+	repo := repositoryFromPath(defaultOrg, config.Repo)
+
+	runs, err := listWorkflowRuns(ctx, repo.FullName, url.Values{"head_sha": {config.RerunSHA}})
+	if err == nil && len(runs) == 0 {
+		err = fmt.Errorf("no workflow run found for commit %s", config.RerunSHA)
+	}
+	if err != nil {
+		errorf("Error finding run of %s at %s: %v\n", repo.FullName, config.RerunSHA, err)
+		result := newResult(repo, "").fail(err)
+		emitResult(result)
+		return []Result{result}, failFastError(result)
+	}
+
+	progressf("Re-running %s run %d of commit %s\n", repo.FullName, runs[0].ID, config.RerunSHA)
+	result := rerunRepoRun(ctx, repoRun{Repo: repo, Run: runs[0]})
+	emitResult(result)
+	if result.err != nil {
+		return []Result{result}, failFastError(result)
+	}
+	return []Result{result}, nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Backoff strategies accepted by -backoff-strategy
+const (
+	BackoffExponential       = "exponential"
+	BackoffExponentialJitter = "exponential+jitter"
+	BackoffFullJitter        = "full-jitter"
+)
+
+// retryBaseDelay is the delay before the first retry. It is a variable so
+// tests can shorten it.
+var retryBaseDelay = time.Second
+
+// retryMaxDelay caps the delay between retries
+const retryMaxDelay = 30 * time.Second
+
+// defaultRetryStatuses are the transient statuses retried unless
+// -retry-status says otherwise: request and gateway timeouts from proxies
+// under load, and server errors
+var defaultRetryStatuses = commaList{"408", "500", "502", "503", "504"}
+
+// isRetryableStatus reports whether a response status is in -retry-status
+func isRetryableStatus(status int) bool {
+	return containsString(config.RetryStatus, strconv.Itoa(status))
+}
+
+// backoffDelay returns how long to wait before retry number attempt (starting
+// at 0) under strategy:
+//
+//   - exponential: base * 2^attempt
+//   - exponential+jitter: half the exponential delay plus a random amount up
+//     to the other half
+//   - full-jitter: a random delay between zero and the exponential delay,
+//     which spreads out workers that failed at the same moment
+//
+// Every delay is capped at retryMaxDelay.
+func backoffDelay(strategy string, attempt int) time.Duration {
+	delay := retryBaseDelay << attempt
+	if delay > retryMaxDelay || delay <= 0 {
+		delay = retryMaxDelay
+	}
+
+	switch strategy {
+	case BackoffExponentialJitter:
+		half := delay / 2
+		return half + time.Duration(rand.Int63n(int64(half)+1))
+	case BackoffFullJitter:
+		return time.Duration(rand.Int63n(int64(delay) + 1))
+	default:
+		return delay
+	}
+}
+
+// maxRetries returns how many times a request with the given method may be
+// retried. Idempotent methods (GET, HEAD, PUT, DELETE) use -retry-get because
+// repeating them cannot change the outcome. POST uses the separate, lower
+// -retry-post: a rerun or dispatch POST that GitHub accepted but whose
+// response was lost would be triggered twice if blindly retried.
+func maxRetries(method string) int {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return config.RetryGet
+	default:
+		return config.RetryPost
+	}
+}
+
+// retryAfterStatus decides whether resp should be retried as attempt number
+// attempt and, if so, how long to wait first
+func retryAfterStatus(resp *http.Response, attempt int) (time.Duration, bool) {
+	if !isRetryableStatus(resp.StatusCode) || attempt >= maxRetries(resp.Request.Method) {
+		return 0, false
+	}
+	return backoffDelay(config.BackoffStrategy, attempt), true
+}
+
+// capServerWait bounds a wait the server asked for, through Retry-After or a
+// rate limit reset time, to -retry-after-cap. A longer wait is shortened to
+// the cap, or fails the request with -abort-on-long-wait.
+func capServerWait(requested time.Duration) (time.Duration, error) {
+	if config.RetryAfterCap <= 0 || requested <= config.RetryAfterCap {
+		return requested, nil
+	}
+	if config.AbortOnLongWait {
+		return 0, fmt.Errorf("rate limit reset too far away: %s, aborting", requested.Round(time.Second))
+	}
+	logger.Warn("server asked for a long wait, capping it", "requested", requested.Round(time.Second), "applied", config.RetryAfterCap)
+	return config.RetryAfterCap, nil
+}
+
+// secondaryRateLimitBackoff is the first wait after a secondary rate limit
+// response without Retry-After; it doubles on each consecutive hit
+const secondaryRateLimitBackoff = 60 * time.Second
+
+// maxSecondaryRetries bounds how often one request waits out a secondary
+// rate limit before its 403 is returned to the caller
+const maxSecondaryRetries = 4
+
+// isSecondaryRateLimit reports whether resp is GitHub's secondary ("abuse
+// detection") rate limit, which is a 403 or 429 whose body mentions it. The
+// body is buffered so callers can still read it.
+func isSecondaryRateLimit(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return false
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return err == nil && strings.Contains(strings.ToLower(string(body)), "secondary rate limit")
+}
+
+// secondaryRateLimitDelay returns how long to wait after the n-th consecutive
+// secondary rate limit: the server's Retry-After when given, otherwise
+// secondaryRateLimitBackoff doubled for each earlier hit
+func secondaryRateLimitDelay(resp *http.Response, n int) time.Duration {
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return secondaryRateLimitBackoff << n
+}
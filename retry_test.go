@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelayBounds(t *testing.T) {
+	resetGlobals(t)
+	retryBaseDelay = time.Second
+
+	tests := []struct {
+		strategy string
+		min, max func(exponential time.Duration) time.Duration
+	}{
+		{
+			strategy: BackoffExponential,
+			min:      func(d time.Duration) time.Duration { return d },
+			max:      func(d time.Duration) time.Duration { return d },
+		},
+		{
+			strategy: "",
+			min:      func(d time.Duration) time.Duration { return d },
+			max:      func(d time.Duration) time.Duration { return d },
+		},
+		{
+			strategy: BackoffExponentialJitter,
+			min:      func(d time.Duration) time.Duration { return d / 2 },
+			max:      func(d time.Duration) time.Duration { return d },
+		},
+		{
+			strategy: BackoffFullJitter,
+			min:      func(d time.Duration) time.Duration { return 0 },
+			max:      func(d time.Duration) time.Duration { return d },
+		},
+	}
+
+	for _, tt := range tests {
+		for _, attempt := range []int{0, 1, 2, 4, 5, 6, 10, 64} {
+			t.Run(fmt.Sprintf("%s/%d", tt.strategy, attempt), func(t *testing.T) {
+				exponential := retryMaxDelay
+				if attempt < 5 {
+					exponential = retryBaseDelay << attempt
+				}
+				lo, hi := tt.min(exponential), tt.max(exponential)
+				for i := 0; i < 200; i++ {
+					if got := backoffDelay(tt.strategy, attempt); got < lo || got > hi {
+						t.Fatalf("backoffDelay = %v, want between %v and %v", got, lo, hi)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestRetryStatuses(t *testing.T) {
+	tests := []struct {
+		status      int
+		retryStatus commaList
+		retried     bool
+	}{
+		{status: http.StatusRequestTimeout, retryStatus: defaultRetryStatuses, retried: true},
+		{status: http.StatusInternalServerError, retryStatus: defaultRetryStatuses, retried: true},
+		{status: http.StatusBadGateway, retryStatus: defaultRetryStatuses, retried: true},
+		{status: http.StatusServiceUnavailable, retryStatus: defaultRetryStatuses, retried: true},
+		{status: http.StatusGatewayTimeout, retryStatus: defaultRetryStatuses, retried: true},
+		{status: http.StatusNotFound, retryStatus: defaultRetryStatuses, retried: false},
+		{status: http.StatusUnprocessableEntity, retryStatus: defaultRetryStatuses, retried: false},
+		{status: http.StatusTooManyRequests, retryStatus: defaultRetryStatuses, retried: false},
+		{status: http.StatusTooManyRequests, retryStatus: commaList{"429"}, retried: true},
+		{status: http.StatusBadGateway, retryStatus: commaList{"429"}, retried: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d with -retry-status %s", tt.status, tt.retryStatus), func(t *testing.T) {
+			var counter requestCounter
+			useTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				counter.add(r)
+				if counter.get("GET /items") == 1 {
+					w.WriteHeader(tt.status)
+					return
+				}
+				w.Write([]byte("[]"))
+			}))
+			config.RetryStatus = tt.retryStatus
+			config.RetryGet = 3
+
+			_, err := makeRequest(context.Background(), "GET", BaseURL+"/items", nil)
+			requests := counter.get("GET /items")
+			if tt.retried {
+				if err != nil || requests != 2 {
+					t.Errorf("got error %v after %d requests, want success after a retry", err, requests)
+				}
+				return
+			}
+			if !isStatus(err, tt.status) || requests != 1 {
+				t.Errorf("got error %v after %d requests, want HTTP %d without a retry", err, requests, tt.status)
+			}
+		})
+	}
+}
+
+func TestRetryStatusGivesUpAfterRetries(t *testing.T) {
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		t.Run(method, func(t *testing.T) {
+			var counter requestCounter
+			useTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				counter.add(r)
+				w.WriteHeader(http.StatusBadGateway)
+			}))
+			config.RetryStatus = defaultRetryStatuses
+			config.RetryGet, config.RetryPost = 3, 1
+
+			_, err := makeRequest(context.Background(), method, BaseURL+"/items", nil)
+			if !isStatus(err, http.StatusBadGateway) {
+				t.Fatalf("error = %v, want HTTP 502", err)
+			}
+			if got, want := counter.get(method+" /items"), maxRetries(method)+1; got != want {
+				t.Errorf("%d requests, want %d", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Runner represents a self-hosted runner registered to a repository
+type Runner struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Busy   bool   `json:"busy"`
+}
+
+// listRunners fetches the self-hosted runners registered to a repository
+func listRunners(ctx context.Context, fullName string) ([]Runner, error) {
+	url := fmt.Sprintf("%s/repos/%s/actions/runners?per_page=100", BaseURL, fullName)
+	return paginate(ctx, url, func(data []byte) ([]Runner, error) {
+		var response struct {
+			Runners []Runner `json:"runners"`
+		}
+		err := json.Unmarshal(data, &response)
+		return response.Runners, err
+	})
+}
+
+// countOnline returns how many of runners are online
+func countOnline(runners []Runner) int {
+	online := 0
+	for _, runner := range runners {
+		if runner.Status == "online" {
+			online++
+		}
+	}
+	return online
+}
+
+// checkRunners reports whether the repository's self-hosted runners are
+// online, flagging repositories whose runners are all offline
+func checkRunners(ctx context.Context, repo Repository) Result {
+	result := newResult(repo, ActionRunnersChecked)
+
+	runners, err := listRunners(ctx, repo.FullName)
+	if err != nil {
+		errorf("Error listing runners for %s: %v\n", repo.Name, err)
+		return result.fail(err)
+	}
+
+	online := countOnline(runners)
+	switch {
+	case len(runners) == 0:
+		result.Reason = "no self-hosted runners"
+	case online == 0:
+		result.Action = ActionRunnersOffline
+		result.Reason = fmt.Sprintf("all %d self-hosted runners offline", len(runners))
+		progressf("%s: %s\n", repo.Name, result.Reason)
+	default:
+		result.Reason = fmt.Sprintf("%d of %d self-hosted runners online", online, len(runners))
+	}
+	return result
+}
+
+// runnersOfflineReason returns a skip reason when every self-hosted runner of
+// the repository is offline, since a rerun would queue forever. Repositories
+// whose runners cannot be listed (the endpoint needs admin access) are not
+// skipped.
+func runnersOfflineReason(ctx context.Context, repo Repository) string {
+	runners, err := listRunners(ctx, repo.FullName)
+	if err != nil {
+		logger.Debug("could not list runners", "repo", repo.Name, "error", err)
+		return ""
+	}
+	if len(runners) > 0 && countOnline(runners) == 0 {
+		return fmt.Sprintf("all %d self-hosted runners offline", len(runners))
+	}
+	return ""
+}
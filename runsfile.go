@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// repositoryFromPath builds a Repository from "name" or "owner/name", using
+// defaultOrg as the owner when none is given
+func repositoryFromPath(defaultOrg, path string) Repository {
+	owner, name, found := strings.Cut(path, "/")
+	if !found {
+		owner, name = defaultOrg, path
+	}
+
+	var repo Repository
+	repo.Name = name
+	repo.FullName = owner + "/" + name
+	repo.Owner.Login = owner
+	return repo
+}
+
+// readRunsFile parses -runs-file, where each non-empty line that is not a #
+// comment is "repo,run_id" and repo is either a name in defaultOrg or
+// "owner/name". A run listed again is read once. Every malformed line is
+// reported in the returned error.
+func readRunsFile(path, defaultOrg string) ([]repoRun, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var targets []repoRun
+	var problems []string
+	seen := make(map[int]bool)
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		repoPath, idText, found := strings.Cut(line, ",")
+		repoPath, idText = strings.TrimSpace(repoPath), strings.TrimSpace(idText)
+		runID, err := strconv.Atoi(idText)
+		switch {
+		case !found:
+			problems = append(problems, fmt.Sprintf("line %d: expected \"repo,run_id\": %q", lineNo, line))
+		case repoPath == "" || strings.Count(repoPath, "/") > 1:
+			problems = append(problems, fmt.Sprintf("line %d: invalid repository %q", lineNo, repoPath))
+		case err != nil || runID <= 0:
+			problems = append(problems, fmt.Sprintf("line %d: invalid run ID %q", lineNo, idText))
+		case seen[runID]:
+			logger.Debug("skipping duplicate run", "line", lineNo, "run_id", runID)
+		default:
+			seen[runID] = true
+			targets = append(targets, repoRun{
+				Repo: repositoryFromPath(defaultOrg, repoPath),
+				Run:  WorkflowRun{ID: runID},
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%d malformed entries in %s:\n  %s", len(problems), path, strings.Join(problems, "\n  "))
+	}
+	return targets, nil
+}
+
+// processRunsFile re-runs exactly the runs listed in -runs-file, skipping
+// repository and run discovery
+func processRunsFile(ctx context.Context, defaultOrg string) ([]Result, error) {
+	targets, err := readRunsFile(config.RunsFile, defaultOrg)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results, firstErr := runPool(ctx, cancel, targets, rerunTarget)
+	summaryf("Re-ran %d of %d runs from %s\n", countAction(results, ActionRerun), len(targets), config.RunsFile)
+
+	if config.FailFast {
+		return results, firstErr
+	}
+	return results, nil
+}
+
+// rerunTarget re-runs a run named explicitly by -runs-file or -run-id. With
+// -verify-runs it first checks that the run exists in the named repository,
+// so a run ID paired with the wrong repository is reported as such rather
+// than as a confusing rerun 404.
+func rerunTarget(ctx context.Context, target repoRun) Result {
+	if config.VerifyRuns {
+		run, err := getWorkflowRun(ctx, target.Repo.FullName, target.Run.ID)
+		if isStatus(err, http.StatusNotFound) {
+			err = fmt.Errorf("run %d not found in %s; check that the run ID belongs to this repository", target.Run.ID, target.Repo.FullName)
+		}
+		if err != nil {
+			errorf("Not re-running %s run %d: %v\n", target.Repo.FullName, target.Run.ID, err)
+			return newResult(target.Repo, "").withRun(target.Run).fail(err)
+		}
+		target.Run = run
+	}
+	return rerunRepoRun(ctx, target)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// resultSchemaJSON is the documented schema of a Result in JSON output
+//
+//go:embed result.schema.json
+var resultSchemaJSON []byte
+
+// jsonSchema is the subset of JSON Schema the -strict-json validator
+// understands: object types with required, properties and
+// additionalProperties, and scalar types
+type jsonSchema struct {
+	Type                 string                `json:"type"`
+	Required             []string              `json:"required"`
+	Properties           map[string]jsonSchema `json:"properties"`
+	AdditionalProperties *bool                 `json:"additionalProperties"`
+}
+
+// resultSchema is the parsed resultSchemaJSON
+var resultSchema = func() jsonSchema {
+	var schema jsonSchema
+	if err := json.Unmarshal(resultSchemaJSON, &schema); err != nil {
+		panic(fmt.Sprintf("invalid embedded result schema: %v", err))
+	}
+	return schema
+}()
+
+// validateResult checks the JSON encoding of result against resultSchema
+func validateResult(result Result) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	return resultSchema.validate("result", value)
+}
+
+// validate checks value against the schema, naming it path in errors
+func (s jsonSchema) validate(path string, value any) error {
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected object, got %T", path, value)
+		}
+		for _, name := range s.Required {
+			if _, ok := object[name]; !ok {
+				return fmt.Errorf("%s: missing required field %q", path, name)
+			}
+		}
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, known := s.Properties[name]
+			if !known {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return fmt.Errorf("%s: unexpected field %q", path, name)
+				}
+				continue
+			}
+			if err := property.validate(path+"."+name, object[name]); err != nil {
+				return err
+			}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s: expected string, got %T", path, value)
+		}
+	case "integer":
+		number, ok := value.(float64)
+		if !ok || number != math.Trunc(number) {
+			return fmt.Errorf("%s: expected integer, got %v", path, value)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s: expected number, got %T", path, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected boolean, got %T", path, value)
+		}
+	}
+	return nil
+}
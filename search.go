@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// searchResultCap is the most results the search API returns for one query,
+// however many match
+const searchResultCap = 1000
+
+// searchRPS paces search requests to the search API's own rate limit of 30
+// requests a minute, which is separate from the core API quota
+const searchRPS = 30.0 / 60
+
+// searchLimiter paces requests to the search API on top of -rps
+var searchLimiter = newRateLimiter(searchRPS)
+
+// isSearchRequest reports whether req goes to the search API
+func isSearchRequest(req *http.Request) bool {
+	return strings.HasPrefix(strings.TrimPrefix(req.URL.Path, "/api/v3"), "/search/")
+}
+
+// eachSearchRepositoryPage lists the repositories of org matching
+// -search-query, passing each page to each. A query matching more than the
+// search API's 1000-result cap fails before any repository is handed out,
+// unless -best-effort-listing accepts the first 1000.
+func eachSearchRepositoryPage(ctx context.Context, org string, each func([]Repository) error) error {
+	query := url.Values{}
+	query.Set("q", "org:"+org+" "+config.SearchQuery)
+	query.Set("per_page", "100")
+	pageURL := fmt.Sprintf("%s/search/repositories?%s", BaseURL, query.Encode())
+
+	first := true
+	return paginateEach(ctx, pageURL, func(data []byte) ([]Repository, error) {
+		var response struct {
+			TotalCount        int          `json:"total_count"`
+			IncompleteResults bool         `json:"incomplete_results"`
+			Items             []Repository `json:"items"`
+		}
+		if err := json.Unmarshal(data, &response); err != nil {
+			return nil, err
+		}
+		if response.IncompleteResults {
+			logger.Warn("search timed out on GitHub's side; results may be incomplete", "org", org)
+		}
+		if first && response.TotalCount > searchResultCap {
+			if !config.BestEffortListing {
+				return nil, fmt.Errorf("search matched %d repositories in %s but the search API returns at most %d; narrow -search-query or use -best-effort-listing", response.TotalCount, org, searchResultCap)
+			}
+			logger.Warn("search matched more repositories than the search API returns", "org", org, "matched", response.TotalCount, "cap", searchResultCap)
+		}
+		first = false
+		return response.Items, nil
+	}, each)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// webhookSecretEnv names the variable -webhook-secret defaults to
+const webhookSecretEnv = "GITHUB_WEBHOOK_SECRET"
+
+// defaultServeCooldown is the -cooldown -serve uses when none is given
+const defaultServeCooldown = 30 * time.Minute
+
+// maxWebhookBody caps a delivery's size; GitHub sends at most 25 MB
+const maxWebhookBody = 25 << 20
+
+// workflowRunEvent is the part of a workflow_run webhook payload -serve uses
+type workflowRunEvent struct {
+	Action      string      `json:"action"`
+	WorkflowRun WorkflowRun `json:"workflow_run"`
+	Repository  Repository  `json:"repository"`
+}
+
+// webhookServer re-runs failed workflow runs as GitHub reports them
+type webhookServer struct {
+	secret []byte
+	ctx    context.Context
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	inFlight map[string]bool
+}
+
+// serveWebhooks listens on addr for workflow_run deliveries and re-runs each
+// completed run that failed, until ctx is cancelled. It also answers the
+// /rerun command in pull request comments from users with write access (see
+// rerunPullRequest). Reruns go through the same -rps and -delay limiters and
+// run filters as a sweep, and the same workflow of a repository is re-run at
+// most once per -cooldown so a workflow that keeps failing cannot start a
+// rerun storm.
+func serveWebhooks(ctx context.Context, addr string) error {
+	s := &webhookServer{
+		secret:   []byte(config.WebhookSecret),
+		ctx:      ctx,
+		inFlight: make(map[string]bool),
+	}
+
+	server := &http.Server{Addr: addr, Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	progressf("Listening for webhooks on %s\n", addr)
+	err := server.ListenAndServe()
+	s.wg.Wait()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// ServeHTTP verifies a delivery and hands a failed run to a background rerun,
+// answering GitHub before the rerun completes
+func (s *webhookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	if !validSignature(s.secret, body, r.Header.Get("X-Hub-Signature-256")) {
+		logger.Warn("rejected webhook with invalid signature", "remote", r.RemoteAddr)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	switch event := r.Header.Get("X-GitHub-Event"); event {
+	case "ping":
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case "workflow_run":
+	case "issue_comment":
+		s.serveComment(w, body)
+		return
+	default:
+		logger.Debug("ignoring webhook event", "event", event)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var event workflowRunEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	if reason := s.ignoreReason(event); reason != "" {
+		logger.Debug("ignoring workflow run", "repo", event.Repository.FullName, "run_id", event.WorkflowRun.ID, "reason", reason)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.rerun(event)
+	}()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// serveComment handles an issue_comment delivery, starting a rerun in the
+// background when the comment is the rerun command on a pull request
+func (s *webhookServer) serveComment(w http.ResponseWriter, body []byte) {
+	var event issueCommentEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	if !isRerunCommand(event) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if len(config.Orgs) > 0 && !containsFold(config.Orgs, event.Repository.Owner.Login) {
+		logger.Debug("ignoring rerun command", "repo", event.Repository.FullName, "reason", "organization not selected by -org")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		rerunPullRequest(s.ctx, event)
+	}()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ignoreReason returns why event should not trigger a rerun, or "" when it
+// should. A run that passes is marked in flight until its rerun is recorded,
+// so a redelivery meanwhile is ignored too.
+func (s *webhookServer) ignoreReason(event workflowRunEvent) string {
+	run, repo := event.WorkflowRun, event.Repository
+	switch {
+	case event.Action != "completed":
+		return "run not completed"
+	case !isFailedConclusion(run.Conclusion):
+		return fmt.Sprintf("run did not fail (conclusion %q)", run.Conclusion)
+	case len(config.Orgs) > 0 && !containsFold(config.Orgs, repo.Owner.Login):
+		return "organization not selected by -org"
+	}
+	if reason := runSkipReason(run); reason != "" {
+		return reason
+	}
+
+	if ago, cooling := checkpointState.InCooldown(repo.FullName, run); cooling {
+		return fmt.Sprintf("in cooldown, re-run %s ago", ago.Round(time.Second))
+	}
+
+	key := repo.FullName + "/" + run.Path
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inFlight[key] {
+		return "rerun of this workflow already in flight"
+	}
+	s.inFlight[key] = true
+	return ""
+}
+
+// rerun re-runs the failed run event reports and saves the checkpoint
+func (s *webhookServer) rerun(event workflowRunEvent) {
+	key := event.Repository.FullName + "/" + event.WorkflowRun.Path
+	defer func() {
+		s.mu.Lock()
+		delete(s.inFlight, key)
+		s.mu.Unlock()
+	}()
+
+	repo := event.Repository
+	if repo.Owner.Login == "" {
+		repo.Owner.Login, _, _ = strings.Cut(repo.FullName, "/")
+	}
+	result := rerunRepoRun(s.ctx, repoRun{Repo: repo, Run: event.WorkflowRun})
+	if result.err != nil {
+		logger.Warn("webhook rerun failed", "repo", repo.FullName, "run_id", event.WorkflowRun.ID, "error", result.err)
+		return
+	}
+	if err := checkpointState.Save(); err != nil {
+		errorf("Error writing checkpoint: %v\n", err)
+	}
+}
+
+// validSignature reports whether header holds the HMAC-SHA256 of body under
+// secret, as GitHub sends it in X-Hub-Signature-256
+func validSignature(secret, body []byte, header string) bool {
+	hexSum, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	sum, err := hex.DecodeString(hexSum)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hmac.Equal(sum, mac.Sum(nil))
+}
+
+// containsFold reports whether list contains value, ignoring case
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
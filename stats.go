@@ -0,0 +1,55 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// requestStats accumulates counters describing how API requests were paced
+type requestStats struct {
+	latency        atomic.Int64
+	limiterWait    atomic.Int64
+	rateLimitWait  atomic.Int64
+	rateLimitWaits atomic.Int64
+	inFlight       atomic.Int64
+	maxInFlight    atomic.Int64
+}
+
+// stats is updated by doRequest and the rate limiter
+var stats requestStats
+
+// begin records a request starting and tracks the peak concurrency
+func (s *requestStats) begin() {
+	n := s.inFlight.Add(1)
+	for {
+		peak := s.maxInFlight.Load()
+		if n <= peak || s.maxInFlight.CompareAndSwap(peak, n) {
+			return
+		}
+	}
+}
+
+// end records a request finishing after d
+func (s *requestStats) end(d time.Duration) {
+	s.inFlight.Add(-1)
+	s.latency.Add(int64(d))
+}
+
+// logStats reports the request counters so users can tune -concurrency and
+// -rps: low concurrency with little waiting means workers are starved, while
+// long limiter or rate-limit waits mean the limits are the bottleneck
+func logStats() {
+	requests := requestCount.Load()
+	var avg time.Duration
+	if requests > 0 {
+		avg = time.Duration(stats.latency.Load() / requests)
+	}
+
+	logger.Info("request stats",
+		"requests", requests,
+		"avg_latency", avg.Round(time.Millisecond),
+		"limiter_wait", time.Duration(stats.limiterWait.Load()).Round(time.Millisecond),
+		"rate_limit_wait", time.Duration(stats.rateLimitWait.Load()).Round(time.Millisecond),
+		"max_concurrency", stats.maxInFlight.Load(),
+	)
+}
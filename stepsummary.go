@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// stepSummaryEnv names the file GitHub Actions renders as the job summary
+const stepSummaryEnv = "GITHUB_STEP_SUMMARY"
+
+// writeStepSummary appends a Markdown table of results to the job summary
+// file when running inside GitHub Actions; elsewhere it does nothing
+func writeStepSummary(results []Result) error {
+	path := os.Getenv(stepSummaryEnv)
+	if path == "" {
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteString("### Workflow re-trigger results\n\n")
+	b.WriteString("| Repository | Conclusion | Action | Details |\n")
+	b.WriteString("| --- | --- | --- | --- |\n")
+	for _, result := range results {
+		repo := result.Repo
+		if result.Org != "" {
+			repo = result.Org + "/" + result.Repo
+		}
+		details := result.Reason
+		if result.Error != "" {
+			details = result.Error
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n",
+			markdownCell(repo), markdownCell(result.Conclusion), markdownCell(result.Action), markdownCell(details))
+	}
+	b.WriteString("\n")
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// markdownCell escapes s for use inside a Markdown table cell
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", " ")
+}
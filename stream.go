@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+)
+
+// resultSink feeds results to an OutputWriter from a single goroutine, so
+// workers can emit concurrently without interleaving output
+type resultSink struct {
+	results chan Result
+	done    chan error
+}
+
+// activeSink receives results while a sweep is running
+var activeSink *resultSink
+
+// startResultSink opens the output destination and starts draining results
+// into the -output writer. Each result is flushed as soon as it is written.
+// With -strict-json the first result that does not match the result schema
+// stops the output and is reported by Close. With -output-append the
+// sweep's results follow an iteration marker line, and the file is synced
+// to disk when the sweep ends so a crash keeps every finished sweep.
+func startResultSink() (*resultSink, error) {
+	dest, err := openOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	bw := bufio.NewWriter(dest)
+	writer, err := newOutputWriter(config.Output, bw)
+	if err != nil {
+		dest.Close()
+		return nil, err
+	}
+
+	s := &resultSink{results: make(chan Result), done: make(chan error, 1)}
+	go func() {
+		var flushErr, schemaErr error
+		if config.OutputAppend {
+			if flushErr = writeIterationMarker(bw); flushErr == nil {
+				flushErr = bw.Flush()
+			}
+		}
+		writer.Start()
+		for result := range s.results {
+			if config.StrictJSON && schemaErr == nil {
+				if schemaErr = validateResult(result); schemaErr != nil {
+					schemaErr = fmt.Errorf("-strict-json: %s/%s: %v", result.Org, result.Repo, schemaErr)
+				}
+			}
+			if schemaErr != nil {
+				continue
+			}
+			writer.Write(result)
+			if flushErr == nil {
+				flushErr = bw.Flush()
+			}
+		}
+		if schemaErr != nil {
+			flushErr = schemaErr
+		}
+		s.done <- finishOutput(writer, bw, dest, flushErr)
+	}()
+	return s, nil
+}
+
+// finishOutput completes the writer and closes the destination, returning the
+// first error encountered
+func finishOutput(writer OutputWriter, bw *bufio.Writer, dest io.Closer, err error) error {
+	if finishErr := writer.Finish(); err == nil {
+		err = finishErr
+	}
+	if flushErr := bw.Flush(); err == nil {
+		err = flushErr
+	}
+	if f, ok := dest.(*os.File); ok && config.OutputAppend {
+		if syncErr := f.Sync(); err == nil {
+			err = syncErr
+		}
+	}
+	if closeErr := dest.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Send queues a result for the writer
+func (s *resultSink) Send(result Result) {
+	s.results <- result
+}
+
+// Close waits for every queued result to be written and the output finished
+func (s *resultSink) Close() error {
+	close(s.results)
+	return <-s.done
+}
+
+// emitResult hands a finished result to the active sink
+func emitResult(result Result) {
+	if activeSink != nil {
+		activeSink.Send(result)
+	}
+}
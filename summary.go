@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Summary holds the aggregate numbers of one sweep for -summary-json. With
+// -group-by-org, Orgs breaks the counts down by organization, and with
+// -track-quota, Quota holds the sweep's quota samples.
+type Summary struct {
+	Org        string    `json:"org"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	SummaryCounts
+	APICalls       int64                    `json:"api_calls"`
+	RateLimitWaits int64                    `json:"rate_limit_waits"`
+	Partial        bool                     `json:"partial"`
+	Orgs           map[string]SummaryCounts `json:"orgs,omitempty"`
+	Quota          []QuotaSample            `json:"quota,omitempty"`
+}
+
+// SummaryCounts tallies results by outcome
+type SummaryCounts struct {
+	Total           int              `json:"total"`
+	Rerun           int              `json:"rerun"`
+	Skipped         int              `json:"skipped"`
+	Errors          int              `json:"errors"`
+	WorkflowMissing int              `json:"workflow_missing"`
+	Conclusions     ConclusionCounts `json:"conclusions"`
+}
+
+// ConclusionCounts tallies the conclusions of the runs results refer to by
+// bucket (see conclusionBucket)
+type ConclusionCounts struct {
+	Pass  int `json:"pass"`
+	Fail  int `json:"fail"`
+	Other int `json:"other"`
+}
+
+// add counts result
+func (c *SummaryCounts) add(result Result) {
+	c.Total++
+	switch {
+	case result.err != nil:
+		c.Errors++
+	case result.Action == ActionRerun:
+		c.Rerun++
+	case result.Action == ActionSkipped:
+		c.Skipped++
+	case result.Action == ActionWorkflowMissing:
+		c.WorkflowMissing++
+	}
+
+	switch conclusionBucket(result.Conclusion) {
+	case BucketPass:
+		c.Conclusions.Pass++
+	case BucketFail:
+		c.Conclusions.Fail++
+	case BucketOther:
+		c.Conclusions.Other++
+	}
+}
+
+// sweepCounters snapshots the request counters at the start of a sweep so
+// the summary reports only that sweep's calls
+type sweepCounters struct {
+	started        time.Time
+	apiCalls       int64
+	rateLimitWaits int64
+	quotaSamples   int
+
+	dryRunMutations int64
+}
+
+// startSweepCounters records the counters as a sweep begins
+func startSweepCounters() sweepCounters {
+	return sweepCounters{
+		started:        time.Now(),
+		apiCalls:       requestCount.Load(),
+		rateLimitWaits: stats.rateLimitWaits.Load(),
+		quotaSamples:   quota.Len(),
+
+		dryRunMutations: dryRunMutations.Load(),
+	}
+}
+
+// buildSummary aggregates results for the organizations in orgs. partial
+// marks a sweep that skipped part of a repository listing.
+func buildSummary(orgs []string, counters sweepCounters, results []Result, partial bool) Summary {
+	summary := Summary{
+		Org:            strings.Join(orgs, ","),
+		StartedAt:      counters.started,
+		FinishedAt:     time.Now(),
+		APICalls:       requestCount.Load() - counters.apiCalls,
+		RateLimitWaits: stats.rateLimitWaits.Load() - counters.rateLimitWaits,
+		Partial:        partial,
+	}
+	if config.GroupByOrg {
+		summary.Orgs = make(map[string]SummaryCounts)
+	}
+	if config.TrackQuota {
+		summary.Quota = quota.Since(counters.quotaSamples)
+	}
+	for _, result := range results {
+		summary.add(result)
+		if summary.Orgs != nil {
+			counts := summary.Orgs[result.Org]
+			counts.add(result)
+			summary.Orgs[result.Org] = counts
+		}
+	}
+	return summary
+}
+
+// printOrgTable prints the per-organization counts of summary followed by a
+// total row
+func printOrgTable(summary Summary) {
+	orgs := make([]string, 0, len(summary.Orgs))
+	for org := range summary.Orgs {
+		orgs = append(orgs, org)
+	}
+	sort.Strings(orgs)
+
+	w := tabwriter.NewWriter(progress, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ORG\tPROCESSED\tRERUN\tSKIPPED\tERRORS\t")
+	row := func(name string, c SummaryCounts) {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t\n", name, c.Total, c.Rerun, c.Skipped, c.Errors)
+	}
+	for _, org := range orgs {
+		row(org, summary.Orgs[org])
+	}
+	row("TOTAL", summary.SummaryCounts)
+	w.Flush()
+}
+
+// printConclusions prints how many of the runs seen fell in each conclusion
+// bucket, or nothing when no completed run was seen
+func printConclusions(c ConclusionCounts) {
+	if c.Pass+c.Fail+c.Other == 0 {
+		return
+	}
+	summaryf("Run conclusions: %d pass, %d fail, %d other\n", c.Pass, c.Fail, c.Other)
+}
+
+// conclusionGroups are the -group-by conclusion headings in print order,
+// each with the conclusion bucket it lists; results without a conclusion
+// are listed under "No runs"
+var conclusionGroups = []struct {
+	heading string
+	bucket  string
+}{
+	{"Failing", BucketFail},
+	{"Passing", BucketPass},
+	{"Other", BucketOther},
+	{"No runs", ""},
+}
+
+// printConclusionGroups lists results under a heading per conclusion bucket,
+// sorted by repository within each, skipping empty groups
+func printConclusionGroups(results []Result) {
+	sorted := append([]Result(nil), results...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Org+"/"+sorted[i].Repo < sorted[j].Org+"/"+sorted[j].Repo
+	})
+
+	for _, group := range conclusionGroups {
+		var lines []string
+		for _, result := range sorted {
+			if conclusionBucket(result.Conclusion) != group.bucket {
+				continue
+			}
+			line := result.Org
+			if result.Repo != "" {
+				line += "/" + result.Repo
+			}
+			if result.Conclusion != "" {
+				line += " (" + result.Conclusion + ")"
+			}
+			if result.Workflow != "" {
+				line += " " + result.Workflow
+			}
+			lines = append(lines, line+": "+result.Action)
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		summaryf("%s (%d):\n", group.heading, len(lines))
+		for _, line := range lines {
+			summaryf("  %s\n", line)
+		}
+	}
+}
+
+// writeSummary writes summary as JSON to path
+func writeSummary(path string, summary Summary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'))
+}
+
+// reportSlowest prints the n results that took longest to process
+func reportSlowest(results []Result, n int) {
+	var timed []Result
+	for _, result := range results {
+		if result.DurationMS > 0 {
+			timed = append(timed, result)
+		}
+	}
+	sort.SliceStable(timed, func(i, j int) bool { return timed[i].DurationMS > timed[j].DurationMS })
+	if len(timed) > n {
+		timed = timed[:n]
+	}
+	if len(timed) == 0 {
+		return
+	}
+
+	summaryf("Slowest repositories:\n")
+	for _, result := range timed {
+		summaryf("  %-40s %s\n", result.Org+"/"+result.Repo, (time.Duration(result.DurationMS) * time.Millisecond).String())
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// stringList is a flag.Value that collects every occurrence of a repeatable flag
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// commaList is a flag.Value holding a comma-separated list; setting it
+// replaces any default
+type commaList []string
+
+func (l *commaList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *commaList) Set(value string) error {
+	*l = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// tokenPool rotates between several tokens as each one exhausts its rate limit
+type tokenPool struct {
+	mu        sync.Mutex
+	tokens    []string
+	remaining map[string]int
+	current   int
+}
+
+// tokens is the pool AuthHeader draws the active token from
+var tokens = newTokenPool([]string{GitHubToken})
+
+// newTokenPool returns a pool over the given tokens, starting with the first
+func newTokenPool(list []string) *tokenPool {
+	return &tokenPool{tokens: list, remaining: make(map[string]int)}
+}
+
+// loadTokens collects the tokens from -token flags and -token-file, falling
+// back to $GITHUB_TOKEN and then GitHubToken when neither is given. An
+// accidentally pasted "Bearer " prefix is stripped, and an empty token is
+// rejected rather than silently sending unauthenticated requests.
+func loadTokens(cfg Config) ([]string, error) {
+	list := append([]string(nil), cfg.Tokens...)
+
+	if cfg.TokenFile != "" {
+		f, err := os.Open(cfg.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open token file: %v", err)
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			list = append(list, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read token file: %v", err)
+		}
+	}
+
+	if len(list) == 0 {
+		if token := os.Getenv(tokenEnv); token != "" {
+			list = []string{token}
+		}
+	}
+	if len(list) == 0 {
+		list = []string{GitHubToken}
+	}
+
+	for i, token := range list {
+		token = stripBearer(strings.TrimSpace(token))
+		if token == "" && cfg.Replay == "" {
+			return nil, fmt.Errorf("no GitHub token configured; set -token, -token-file, $GITHUB_TOKEN or GitHubToken (unauthenticated requests hide private repositories)")
+		}
+		list[i] = token
+	}
+	return list, nil
+}
+
+// stripBearer removes a leading "Bearer" scheme from token, leaving "" when
+// the token is nothing but the scheme
+func stripBearer(token string) string {
+	const scheme = "bearer"
+	if len(token) < len(scheme) || !strings.EqualFold(token[:len(scheme)], scheme) {
+		return token
+	}
+	rest := token[len(scheme):]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return token
+	}
+	return strings.TrimSpace(rest)
+}
+
+// Current returns the token requests should use right now
+func (p *tokenPool) Current() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.tokens[p.current]
+}
+
+// Remaining returns the last reported quota of the current token; ok is false
+// until a response for that token has been seen
+func (p *tokenPool) Remaining() (remaining int, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	remaining, ok = p.remaining[p.tokens[p.current]]
+	return remaining, ok
+}
+
+// Observe records the quota reported in resp for token. It returns true when
+// the token is exhausted and the pool switched to another token with quota
+// left. Only the core API quota is tracked; the search API's separate quota
+// is paced by searchLimiter instead.
+func (p *tokenPool) Observe(token string, resp *http.Response) bool {
+	value := resp.Header.Get("X-RateLimit-Remaining")
+	if value == "" {
+		return false
+	}
+	if resource := resp.Header.Get("X-RateLimit-Resource"); resource != "" && resource != "core" {
+		return false
+	}
+	remaining, err := strconv.Atoi(value)
+	if err != nil {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.remaining[token] = remaining
+	if remaining > 0 {
+		return false
+	}
+	if p.tokens[p.current] != token {
+		return true
+	}
+
+	for i := 1; i < len(p.tokens); i++ {
+		next := (p.current + i) % len(p.tokens)
+		if left, seen := p.remaining[p.tokens[next]]; !seen || left > 0 {
+			p.current = next
+			logger.Info("rotating token after rate limit", "token_index", next)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadTokensStripsBearer(t *testing.T) {
+	tokens, err := loadTokens(Config{Tokens: []string{"Bearer abc", "bearer\tdef", " ghi ", "Bearerjkl"}})
+	if err != nil {
+		t.Fatalf("loadTokens: %v", err)
+	}
+	if want := []string{"abc", "def", "ghi", "Bearerjkl"}; !reflect.DeepEqual(tokens, want) {
+		t.Errorf("loadTokens = %q, want %q", tokens, want)
+	}
+}
+
+func TestLoadTokensRejectsEmpty(t *testing.T) {
+	t.Setenv(tokenEnv, "")
+	for _, token := range []string{"", "  ", "Bearer ", "Bearer", "bearer\t"} {
+		if _, err := loadTokens(Config{Tokens: []string{token}}); err == nil {
+			t.Errorf("loadTokens(%q) succeeded, want a missing token error", token)
+		}
+	}
+}
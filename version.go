@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Build information, set at link time with
+//
+//	go build -ldflags "-X main.version=v1.2.3 -X main.commit=$(git rev-parse HEAD) -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildDate = "unknown"
+)
+
+// printVersion prints the build information and the Go runtime version
+func printVersion() {
+	fmt.Printf("version:    %s\n", version)
+	fmt.Printf("commit:     %s\n", commit)
+	fmt.Printf("built:      %s\n", buildDate)
+	fmt.Printf("go version: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+)
+
+// Workflow represents a workflow definition in a repository
+type Workflow struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Path  string `json:"path"`
+	State string `json:"state"`
+}
+
+// listWorkflows fetches all workflows defined in a repository
+func listWorkflows(ctx context.Context, fullName string) ([]Workflow, error) {
+	url := fmt.Sprintf("%s/repos/%s/actions/workflows?per_page=100", BaseURL, fullName)
+	return paginate(ctx, url, func(data []byte) ([]Workflow, error) {
+		var response struct {
+			Workflows []Workflow `json:"workflows"`
+		}
+		err := json.Unmarshal(data, &response)
+		return response.Workflows, err
+	})
+}
+
+// findWorkflowByFile returns the workflow whose file name (or full path) is file
+func findWorkflowByFile(workflows []Workflow, file string) (Workflow, bool) {
+	for _, workflow := range workflows {
+		if workflow.Path == file || path.Base(workflow.Path) == file {
+			return workflow, true
+		}
+	}
+	return Workflow{}, false
+}
+
+// listRepositoryWorkflows reports each workflow of the repository for
+// -list-workflows, one result per workflow with its state as the reason, and
+// prints them as a table with text output. It makes no changes.
+func listRepositoryWorkflows(ctx context.Context, repo Repository) Result {
+	result := newResult(repo, ActionWorkflowListed)
+
+	workflows, err := listWorkflows(ctx, repo.FullName)
+	if err != nil {
+		errorf("Error listing workflows for %s: %v\n", repo.Name, err)
+		return result.fail(err)
+	}
+	if len(workflows) == 0 {
+		progressf("%s: no workflows\n", repo.FullName)
+		return result.skip("no workflows")
+	}
+
+	progressf("%s:\n", repo.FullName)
+	for _, workflow := range workflows {
+		progressf("  %-30s %-50s %s\n", workflow.Name, workflow.Path, workflow.State)
+		workflowResult := newResult(repo, ActionWorkflowListed)
+		workflowResult.Workflow = workflow.Name
+		workflowResult.WorkflowPath = workflow.Path
+		workflowResult.Reason = workflow.State
+		emitResult(workflowResult)
+	}
+	result.Reason = fmt.Sprintf("%d workflows", len(workflows))
+	return result
+}
+
+// missingWorkflowReason returns a skip reason when -only-repos-with-workflow
+// is set and the repository has no workflow with that file name. The list is
+// fetched through responseCache, so a mode that lists the workflows again in
+// the same sweep does not pay for it twice.
+func missingWorkflowReason(ctx context.Context, repo Repository) (string, error) {
+	file := config.OnlyReposWithWorkflow
+	if file == "" {
+		return "", nil
+	}
+	workflows, err := listWorkflows(ctx, repo.FullName)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := findWorkflowByFile(workflows, file); !ok {
+		return fmt.Sprintf("no workflow %s (-only-repos-with-workflow)", file), nil
+	}
+	return "", nil
+}
+
+// enableWorkflow enables a disabled workflow
+func enableWorkflow(ctx context.Context, fullName string, workflowID int) error {
+	url := fmt.Sprintf("%s/repos/%s/actions/workflows/%d/enable", BaseURL, fullName, workflowID)
+	_, err := makeRequest(forOperation(ctx, opEnableWorkflow), "PUT", url, nil)
+	return err
+}
+
+// disableWorkflow disables a workflow so it no longer runs
+func disableWorkflow(ctx context.Context, fullName string, workflowID int) error {
+	url := fmt.Sprintf("%s/repos/%s/actions/workflows/%d/disable", BaseURL, fullName, workflowID)
+	_, err := makeRequest(forOperation(ctx, opDisableWorkflow), "PUT", url, nil)
+	return err
+}
+
+// toggleWorkflow enables or disables the workflow named by -enable-workflow or
+// -disable-workflow in a repository, skipping repositories that lack it
+func toggleWorkflow(ctx context.Context, repo Repository) Result {
+	file, enable := config.DisableWorkflow, false
+	result := newResult(repo, ActionWorkflowDisabled)
+	if config.EnableWorkflow != "" {
+		file, enable = config.EnableWorkflow, true
+		result.Action = ActionWorkflowEnabled
+	}
+
+	workflows, err := listWorkflows(ctx, repo.FullName)
+	if err != nil {
+		errorf("Error listing workflows for %s: %v\n", repo.Name, err)
+		return result.fail(err)
+	}
+
+	workflow, ok := findWorkflowByFile(workflows, file)
+	if !ok {
+		progressf("Skipping %s: no workflow %s\n", repo.Name, file)
+		return result.skip("workflow not found")
+	}
+	result.Workflow = workflow.Path
+
+	if enable {
+		err = enableWorkflow(ctx, repo.FullName, workflow.ID)
+	} else {
+		err = disableWorkflow(ctx, repo.FullName, workflow.ID)
+	}
+	if err != nil {
+		errorf("Failed to toggle workflow %s in %s: %v\n", workflow.Path, repo.Name, err)
+		return result.fail(err)
+	}
+
+	if enable {
+		progressf("Enabled workflow %s in %s\n", workflow.Path, repo.Name)
+	} else {
+		progressf("Disabled workflow %s in %s\n", workflow.Path, repo.Name)
+	}
+	return result
+}
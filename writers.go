@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+// OutputWriter renders results in one output format. Start is called before
+// the first result and Finish after the last; Write may buffer or emit
+// immediately. Write errors are reported by Finish.
+type OutputWriter interface {
+	Start()
+	Write(Result)
+	Finish() error
+}
+
+// newOutputWriter returns the writer for format, rendering to w
+func newOutputWriter(format string, w io.Writer) (OutputWriter, error) {
+	switch format {
+	case "text":
+		return &textWriter{}, nil
+	case "json":
+		return &jsonWriter{w: w}, nil
+	case "jsonl":
+		return &jsonlWriter{enc: json.NewEncoder(w)}, nil
+	case "csv":
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case "template":
+		tmpl, err := template.New("output").Parse(config.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -template: %v", err)
+		}
+		return &templateWriter{w: w, tmpl: tmpl}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// textWriter writes nothing: in text mode the progress lines on stdout are the
+// output
+type textWriter struct{}
+
+func (*textWriter) Start()        {}
+func (*textWriter) Write(Result)  {}
+func (*textWriter) Finish() error { return nil }
+
+// jsonWriter buffers every result and writes them as one indented JSON array
+type jsonWriter struct {
+	w       io.Writer
+	results []Result
+}
+
+func (j *jsonWriter) Start() {
+	j.results = []Result{}
+}
+
+func (j *jsonWriter) Write(result Result) {
+	j.results = append(j.results, result)
+}
+
+func (j *jsonWriter) Finish() error {
+	enc := json.NewEncoder(j.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(j.results)
+}
+
+// jsonlWriter writes each result as a JSON object on its own line as soon as
+// it arrives
+type jsonlWriter struct {
+	enc *json.Encoder
+	err error
+}
+
+func (j *jsonlWriter) Start() {}
+
+func (j *jsonlWriter) Write(result Result) {
+	if j.err == nil {
+		j.err = j.enc.Encode(result)
+	}
+}
+
+func (j *jsonlWriter) Finish() error {
+	return j.err
+}
+
+// csvWriter writes a header row followed by one row per result
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) Start() {
+	c.w.Write([]string{"org", "repo", "action", "workflow", "run_id", "reason", "error", "conclusion", "rerun_reason", "check_suite_id", "workflow_path", "duration_ms", "owners"})
+}
+
+func (c *csvWriter) Write(r Result) {
+	c.w.Write([]string{r.Org, r.Repo, r.Action, r.Workflow, strconv.Itoa(r.RunID), r.Reason, r.Error, r.Conclusion, r.RerunReason, strconv.FormatInt(r.CheckSuiteID, 10), r.WorkflowPath, strconv.FormatInt(r.DurationMS, 10), r.Owners})
+}
+
+func (c *csvWriter) Finish() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// templateWriter executes the -template once per result
+type templateWriter struct {
+	w    io.Writer
+	tmpl *template.Template
+	err  error
+}
+
+func (t *templateWriter) Start() {}
+
+func (t *templateWriter) Write(result Result) {
+	if t.err == nil {
+		t.err = t.tmpl.Execute(t.w, result)
+	}
+}
+
+func (t *templateWriter) Finish() error {
+	return t.err
+}
+
+// sweepIteration numbers the sweeps of this invocation from 1, for the
+// -output-append iteration markers
+var sweepIteration int
+
+// iterationMarker separates the sweeps of an -output-append file
+type iterationMarker struct {
+	Iteration int       `json:"iteration"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// writeIterationMarker writes the marker line that starts the current
+// sweep's results in an -output-append file
+func writeIterationMarker(w io.Writer) error {
+	return json.NewEncoder(w).Encode(iterationMarker{Iteration: sweepIteration, Timestamp: time.Now()})
+}
+
+// openOutput returns the destination for structured output: stdout, or
+// -output-file. Files are written atomically, except jsonl which streams
+// straight into the file so completed lines survive a crash, and which
+// -output-append adds to instead of replacing.
+func openOutput() (io.WriteCloser, error) {
+	if config.OutputFile == "" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	if config.OutputAppend {
+		if err := os.MkdirAll(filepath.Dir(config.OutputFile), 0o755); err != nil {
+			return nil, err
+		}
+		return os.OpenFile(config.OutputFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	}
+	if config.Output == "jsonl" {
+		if err := os.MkdirAll(filepath.Dir(config.OutputFile), 0o755); err != nil {
+			return nil, err
+		}
+		return os.Create(config.OutputFile)
+	}
+	return createAtomicFile(config.OutputFile)
+}
+
+// nopWriteCloser keeps stdout open when the output is closed
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOutputWriters(t *testing.T) {
+	results := []Result{
+		{Org: "acme", Repo: "r1", Action: ActionRerun, Workflow: "CI", WorkflowPath: ".github/workflows/ci.yml", RunID: 11, Conclusion: "failure", DurationMS: 1500},
+		{Org: "acme", Repo: "r2", Action: ActionSkipped, Reason: "latest run succeeded, \"ok\""},
+	}
+
+	tests := []struct {
+		format   string
+		template string
+		want     string
+	}{
+		{format: "text", want: ""},
+		{
+			format: "json",
+			want: `[
+  {
+    "org": "acme",
+    "repo": "r1",
+    "action": "rerun",
+    "workflow": "CI",
+    "workflow_path": ".github/workflows/ci.yml",
+    "run_id": 11,
+    "conclusion": "failure",
+    "duration_ms": 1500
+  },
+  {
+    "org": "acme",
+    "repo": "r2",
+    "action": "skipped",
+    "reason": "latest run succeeded, \"ok\""
+  }
+]
+`,
+		},
+		{
+			format: "jsonl",
+			want: `{"org":"acme","repo":"r1","action":"rerun","workflow":"CI","workflow_path":".github/workflows/ci.yml","run_id":11,"conclusion":"failure","duration_ms":1500}
+{"org":"acme","repo":"r2","action":"skipped","reason":"latest run succeeded, \"ok\""}
+`,
+		},
+		{
+			format: "csv",
+			want: `org,repo,action,workflow,run_id,reason,error,conclusion,rerun_reason,check_suite_id,workflow_path,duration_ms,owners
+acme,r1,rerun,CI,11,,,failure,,0,.github/workflows/ci.yml,1500,
+acme,r2,skipped,,0,"latest run succeeded, ""ok""",,,,0,,0,
+`,
+		},
+		{
+			format:   "template",
+			template: "{{.Repo}} {{.Action}}\n",
+			want:     "r1 rerun\nr2 skipped\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			resetGlobals(t)
+			config.Template = tt.template
+
+			var buf bytes.Buffer
+			w, err := newOutputWriter(tt.format, &buf)
+			if err != nil {
+				t.Fatalf("newOutputWriter: %v", err)
+			}
+			w.Start()
+			for _, result := range results {
+				w.Write(result)
+			}
+			if err := w.Finish(); err != nil {
+				t.Fatalf("Finish: %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("%s output:\n%s\nwant:\n%s", tt.format, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONWriterWithoutResults(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := newOutputWriter("json", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Start()
+	if err := w.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "[]\n" {
+		t.Errorf("empty json output = %q, want %q", got, "[]\n")
+	}
+}